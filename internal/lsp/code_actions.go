@@ -0,0 +1,320 @@
+package lsp
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/agext/levenshtein"
+	hclschema "github.com/hashicorp/hcl-lang/schema"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/loczek/nomad-ls/internal/schema"
+	"github.com/zclconf/go-cty/cty"
+	"go.lsp.dev/protocol"
+)
+
+// MissingAttribute is a required attribute that is not set in a block body.
+type MissingAttribute struct {
+	Name   string
+	Schema *hclschema.AttributeSchema
+	Body   *hclsyntax.Body
+	Block  *hcl.Block
+}
+
+func CollectMissingAttributes(body hcl.Body, ctx *hcl.EvalContext) []MissingAttribute {
+	var missing []MissingAttribute
+
+	CollectMissingAttributesDFS(body, nil, &missing, &schema.RootBodySchema, ctx)
+
+	return missing
+}
+
+func CollectMissingAttributesDFS(body hcl.Body, block *hcl.Block, missing *[]MissingAttribute, langSchema *hclschema.BodySchema, ctx *hcl.EvalContext) {
+	if langSchema == nil {
+		return
+	}
+
+	bodyContent, _, _ := body.PartialContent(langSchema.ToHCLSchema())
+
+	if syntaxBody, ok := body.(*hclsyntax.Body); ok && block != nil {
+		names := make([]string, 0, len(langSchema.Attributes))
+		for k := range langSchema.Attributes {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+
+		for _, k := range names {
+			if langSchema.Attributes[k].IsRequired && bodyContent.Attributes[k] == nil {
+				*missing = append(*missing, MissingAttribute{
+					Name:   k,
+					Schema: langSchema.Attributes[k],
+					Body:   syntaxBody,
+					Block:  block,
+				})
+			}
+		}
+	}
+
+	for _, b := range bodyContent.Blocks {
+		if langSchema.Blocks[b.Type] != nil && langSchema.Blocks[b.Type].Body != nil {
+			CollectMissingAttributesDFS(b.Body, b, missing, langSchema.Blocks[b.Type].Body, ctx)
+		} else if langSchema.Blocks[b.Type] != nil && langSchema.Blocks[b.Type].DependentBody != nil {
+			CollectMissingAttributesDFS(b.Body, b, missing, dependentBodySchema(langSchema.Blocks[b.Type], bodyContent, ctx), ctx)
+		}
+	}
+}
+
+// UnknownName is an attribute or block type that is not part of the schema of
+// the body it appears in, along with the closest valid name if any.
+type UnknownName struct {
+	Name       string
+	Range      hcl.Range
+	Suggestion string
+}
+
+func CollectUnknownNames(body hcl.Body, ctx *hcl.EvalContext) []UnknownName {
+	var unknown []UnknownName
+
+	CollectUnknownNamesDFS(body, &unknown, &schema.RootBodySchema, ctx)
+
+	return unknown
+}
+
+func CollectUnknownNamesDFS(body hcl.Body, unknown *[]UnknownName, langSchema *hclschema.BodySchema, ctx *hcl.EvalContext) {
+	syntaxBody, ok := body.(*hclsyntax.Body)
+	if langSchema == nil || !ok {
+		return
+	}
+
+	bodyContent, _, _ := body.PartialContent(langSchema.ToHCLSchema())
+
+	if langSchema.AnyAttribute == nil {
+		candidates := make([]string, 0, len(langSchema.Attributes))
+		for k := range langSchema.Attributes {
+			candidates = append(candidates, k)
+		}
+
+		for name, attr := range syntaxBody.Attributes {
+			if _, ok := langSchema.Attributes[name]; !ok {
+				*unknown = append(*unknown, UnknownName{
+					Name:       name,
+					Range:      attr.NameRange,
+					Suggestion: closestName(name, candidates),
+				})
+			}
+		}
+	}
+
+	candidates := make([]string, 0, len(langSchema.Blocks))
+	for k := range langSchema.Blocks {
+		candidates = append(candidates, k)
+	}
+
+	for _, b := range syntaxBody.Blocks {
+		if _, ok := langSchema.Blocks[b.Type]; !ok {
+			*unknown = append(*unknown, UnknownName{
+				Name:       b.Type,
+				Range:      b.TypeRange,
+				Suggestion: closestName(b.Type, candidates),
+			})
+		}
+	}
+
+	for _, b := range bodyContent.Blocks {
+		if langSchema.Blocks[b.Type] != nil && langSchema.Blocks[b.Type].Body != nil {
+			CollectUnknownNamesDFS(b.Body, unknown, langSchema.Blocks[b.Type].Body, ctx)
+		} else if langSchema.Blocks[b.Type] != nil && langSchema.Blocks[b.Type].DependentBody != nil {
+			CollectUnknownNamesDFS(b.Body, unknown, dependentBodySchema(langSchema.Blocks[b.Type], bodyContent, ctx), ctx)
+		}
+	}
+}
+
+// closestName returns the candidate closest to name by edit distance, or an
+// empty string when none is close enough to be a plausible typo.
+func closestName(name string, candidates []string) string {
+	// sorted copy, so that ties resolve the same way whatever the order
+	candidates = slices.Sorted(slices.Values(candidates))
+
+	best := ""
+	bestDistance := max(2, len(name)/3) + 1
+
+	for _, candidate := range candidates {
+		distance := levenshtein.Distance(name, candidate, nil)
+		if distance < bestDistance {
+			best = candidate
+			bestDistance = distance
+		}
+	}
+
+	return best
+}
+
+// CollectCodeActions returns the quick fixes for the given diagnostics, which
+// are the diagnostics the client sent along with the code action request.
+// The schema of blocks is resolved in ctx, like for diagnostics.
+func CollectCodeActions(file *hcl.File, documentURI protocol.DocumentURI, diagnostics []protocol.Diagnostic, ctx *hcl.EvalContext) []protocol.CodeAction {
+	actions := []protocol.CodeAction{}
+
+	missing := CollectMissingAttributes(file.Body, ctx)
+	unknown := CollectUnknownNames(file.Body, ctx)
+
+	for _, d := range diagnostics {
+		for _, name := range unknown {
+			if name.Suggestion == "" || protocolRange(name.Range) != d.Range {
+				continue
+			}
+
+			actions = append(actions, protocol.CodeAction{
+				Title:       fmt.Sprintf("Did you mean %q?", name.Suggestion),
+				Kind:        protocol.QuickFix,
+				Diagnostics: []protocol.Diagnostic{d},
+				IsPreferred: true,
+				Edit: &protocol.WorkspaceEdit{
+					Changes: map[protocol.DocumentURI][]protocol.TextEdit{
+						documentURI: {{Range: d.Range, NewText: name.Suggestion}},
+					},
+				},
+			})
+		}
+
+		for _, attr := range missing {
+			if protocolRange(attr.Body.MissingItemRange()).Start != d.Range.Start {
+				continue
+			}
+
+			if !strings.Contains(d.Message, fmt.Sprintf("%q", attr.Name)) {
+				continue
+			}
+
+			actions = append(actions, protocol.CodeAction{
+				Title:       fmt.Sprintf("Add missing attribute %q", attr.Name),
+				Kind:        protocol.QuickFix,
+				Diagnostics: []protocol.Diagnostic{d},
+				IsPreferred: true,
+				Edit: &protocol.WorkspaceEdit{
+					Changes: map[protocol.DocumentURI][]protocol.TextEdit{
+						documentURI: {MissingAttributeEdit(attr, file.Bytes)},
+					},
+				},
+			})
+		}
+	}
+
+	return actions
+}
+
+// MissingAttributeEdit returns the edit inserting attr, set to its default
+// value, as the first line of its block body. Workspace edits cannot contain
+// snippets, so the default value is inserted as is for the user to adjust.
+func MissingAttributeEdit(attr MissingAttribute, src []byte) protocol.TextEdit {
+	blockIndent := lineIndent(src, attr.Block.DefRange.Start.Line)
+
+	indent := blockIndent + "  "
+	if first := firstItemLine(attr.Body); first > attr.Body.SrcRange.Start.Line {
+		indent = lineIndent(src, first)
+	}
+
+	text := fmt.Sprintf("\n%s%s = %s", indent, attr.Name, attributeValueText(attr.Schema))
+
+	// the block is written on a single line, e.g. `config {}`
+	if attr.Body.SrcRange.Start.Line == attr.Body.SrcRange.End.Line {
+		text += "\n" + blockIndent
+	}
+
+	openBrace := attr.Body.SrcRange
+	openBrace.Start.Column += 1
+	openBrace.Start.Byte += 1
+
+	pos := protocolRange(openBrace).Start
+
+	return protocol.TextEdit{
+		Range:   protocol.Range{Start: pos, End: pos},
+		NewText: text,
+	}
+}
+
+// attributeValueText renders the default value of an attribute as HCL. An
+// attribute without a default gets the first of its allowed values, or the
+// zero value of its type when any value is allowed.
+func attributeValueText(attrSchema *hclschema.AttributeSchema) string {
+	if val, ok := defaultValue(attrSchema); ok {
+		return formatValue(val)
+	}
+
+	for _, v := range enumValues(attrSchema.Constraint) {
+		// values ending in a slash, such as `cni/`, are prefixes
+		if !strings.HasSuffix(v, "/") {
+			return formatValue(cty.StringVal(v))
+		}
+	}
+
+	t, _ := constraintType(attrSchema.Constraint)
+
+	switch {
+	case t == cty.Number:
+		return "0"
+	case t == cty.Bool:
+		return "false"
+	case t.IsListType() || t.IsSetType() || t.IsTupleType():
+		return "[]"
+	case t.IsMapType() || t.IsObjectType():
+		return "{}"
+	default:
+		return "\"\""
+	}
+}
+
+// defaultValue returns the static default value of an attribute, which the
+// schema declares either as a DefaultValue or as a pointer to one.
+func defaultValue(attrSchema *hclschema.AttributeSchema) (cty.Value, bool) {
+	var val cty.Value
+
+	switch d := attrSchema.DefaultValue.(type) {
+	case *hclschema.DefaultValue:
+		val = d.Value
+	case hclschema.DefaultValue:
+		val = d.Value
+	}
+
+	return val, val != cty.NilVal
+}
+
+// formatValue renders a known value as HCL source.
+func formatValue(val cty.Value) string {
+	return string(hclwrite.TokensForValue(val).Bytes())
+}
+
+// firstItemLine returns the line of the first attribute or block in body, or
+// zero when the body is empty.
+func firstItemLine(body *hclsyntax.Body) int {
+	line := 0
+
+	for _, attr := range body.Attributes {
+		if line == 0 || attr.SrcRange.Start.Line < line {
+			line = attr.SrcRange.Start.Line
+		}
+	}
+
+	for _, b := range body.Blocks {
+		if line == 0 || b.TypeRange.Start.Line < line {
+			line = b.TypeRange.Start.Line
+		}
+	}
+
+	return line
+}
+
+// lineIndent returns the leading whitespace of the 1-based line in src.
+func lineIndent(src []byte, line int) string {
+	lines := strings.Split(string(src), "\n")
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+
+	content := lines[line-1]
+
+	return content[:len(content)-len(strings.TrimLeft(content, " \t"))]
+}
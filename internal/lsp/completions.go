@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"log"
 	"strconv"
+	"strings"
+	"unicode"
 
 	hclschema "github.com/hashicorp/hcl-lang/schema"
 	"github.com/hashicorp/hcl/v2"
@@ -14,10 +16,10 @@ import (
 	"go.lsp.dev/protocol"
 )
 
-func CollectCompletions(body hcl.Body, pos hcl.Pos) []protocol.CompletionItem {
+func CollectCompletions(body hcl.Body, pos hcl.Pos, ctx *hcl.EvalContext) []protocol.CompletionItem {
 	var blocks []protocol.CompletionItem
 
-	CollectCompletionsDFS(body, &blocks, pos, &schema.RootBodySchema, 1)
+	CollectCompletionsDFS(body, &blocks, pos, &schema.RootBodySchema, 1, ctx)
 
 	return blocks
 }
@@ -28,6 +30,7 @@ func CollectCompletionsDFS(
 	pos hcl.Pos,
 	langSchema *hclschema.BodySchema,
 	depth int,
+	ctx *hcl.EvalContext,
 ) {
 	if langSchema == nil {
 		return
@@ -48,18 +51,19 @@ func CollectCompletionsDFS(
 			matchingBlocks += 1
 
 			if langSchema.Blocks[k] != nil && langSchema.Blocks[k].Body != nil {
-				CollectCompletionsDFS(b.Body, blocks, pos, langSchema.Blocks[k].Body, depth+1)
+				CollectCompletionsDFS(b.Body, blocks, pos, langSchema.Blocks[k].Body, depth+1, ctx)
 			} else if langSchema.Blocks[k] != nil && langSchema.Blocks[k].DependentBody != nil {
-				if bodyContent.Attributes["driver"] != nil {
-					driver, _ := bodyContent.Attributes["driver"].Expr.Value(&hcl.EvalContext{})
-
-					CollectCompletionsDFS(b.Body, blocks, pos, langSchema.Blocks[k].DependentBody[hclschema.SchemaKey(driver.AsString())], depth+1)
-				}
+				CollectCompletionsDFS(b.Body, blocks, pos, dependentBodySchema(langSchema.Blocks[k], bodyContent, ctx), depth+1, ctx)
 			}
 		}
 	}
 
 	if matchingBlocks == 0 {
+		if items, ok := collectValueCompletions(bodyContent, pos, langSchema); ok {
+			*blocks = append(*blocks, items...)
+			return
+		}
+
 		var blocksByTypeArr []protocol.CompletionItem
 
 		for k, v := range langSchema.Blocks {
@@ -88,6 +92,36 @@ func CollectCompletionsDFS(
 				continue
 			}
 
+			if _, ok := v.Constraint.(hclschema.TypeDeclaration); ok && bodyContent.Attributes[k] == nil {
+				blocksByTypeArr = append(blocksByTypeArr, protocol.CompletionItem{
+					Label:      k,
+					Kind:       protocol.CompletionItemKindVariable,
+					InsertText: fmt.Sprintf("%s = ${0:string}", k),
+					Detail:     "type",
+					Documentation: protocol.MarkupContent{
+						Kind:  protocol.Markdown,
+						Value: v.Description.Value,
+					},
+					InsertTextFormat: protocol.InsertTextFormatSnippet,
+				})
+				continue
+			}
+
+			if values := enumValues(v.Constraint); len(values) > 0 && bodyContent.Attributes[k] == nil {
+				blocksByTypeArr = append(blocksByTypeArr, protocol.CompletionItem{
+					Label:      k,
+					Kind:       protocol.CompletionItemKindVariable,
+					InsertText: fmt.Sprintf("%s = \"${1|%s|}\"", k, strings.Join(values, ",")),
+					Detail:     v.Constraint.FriendlyName(),
+					Documentation: protocol.MarkupContent{
+						Kind:  protocol.Markdown,
+						Value: v.Description.Value,
+					},
+					InsertTextFormat: protocol.InsertTextFormatSnippet,
+				})
+				continue
+			}
+
 			c, ok := v.Constraint.(*hclschema.LiteralType)
 			if !ok {
 				continue
@@ -113,13 +147,13 @@ func CollectCompletionsDFS(
 				continue
 			}
 
-			d, ok := v.DefaultValue.(*hclschema.DefaultValue)
+			d, ok := defaultValue(v)
 			if ok {
 				switch c.Type {
 				case cty.String:
-					insertText = fmt.Sprintf("%s = \"${0:%s}\"", k, d.Value.AsString())
+					insertText = fmt.Sprintf("%s = \"${0:%s}\"", k, d.AsString())
 				case cty.Number:
-					val, err := convert.Convert(d.Value, cty.String)
+					val, err := convert.Convert(d, cty.String)
 
 					if err != nil {
 						continue
@@ -127,11 +161,11 @@ func CollectCompletionsDFS(
 
 					insertText = fmt.Sprintf("%s = ${0:%s}", k, val.AsString())
 				case cty.Bool:
-					insertText = fmt.Sprintf("%s = ${0:%s}", k, strconv.FormatBool(d.Value.True()))
+					insertText = fmt.Sprintf("%s = ${0:%s}", k, strconv.FormatBool(d.True()))
 				case cty.List(cty.String):
 					var arr []string
 
-					for _, b := range d.Value.Elements() {
+					for _, b := range d.Elements() {
 						arr = append(arr, b.AsString())
 					}
 
@@ -139,7 +173,7 @@ func CollectCompletionsDFS(
 				case cty.Map(cty.String):
 					var arr = make(map[string]string)
 
-					for a, b := range d.Value.Elements() {
+					for a, b := range d.Elements() {
 						arr[a.AsString()] = b.AsString()
 					}
 
@@ -168,6 +202,54 @@ func CollectCompletionsDFS(
 	log.Printf("matching blocks: %d", matchingBlocks)
 }
 
+// collectValueCompletions returns the values enumerated by the schema of the
+// attribute of bodyContent whose value contains pos. The boolean result is
+// false when pos is not within the value of such an attribute.
+func collectValueCompletions(bodyContent *hcl.BodyContent, pos hcl.Pos, langSchema *hclschema.BodySchema) ([]protocol.CompletionItem, bool) {
+	for name, attr := range bodyContent.Attributes {
+		r := attr.Expr.Range()
+		if pos.Byte < r.Start.Byte || pos.Byte > r.End.Byte || langSchema.Attributes[name] == nil {
+			continue
+		}
+
+		values := enumValues(langSchema.Attributes[name].Constraint)
+		if len(values) == 0 {
+			continue
+		}
+
+		quoted := false
+		if _, ok := attr.Expr.(*hclsyntax.TemplateExpr); ok && pos.Byte > r.Start.Byte && pos.Byte < r.End.Byte {
+			r.Start.Byte += 1
+			r.Start.Column += 1
+			r.End.Byte -= 1
+			r.End.Column -= 1
+			quoted = true
+		}
+
+		items := []protocol.CompletionItem{}
+
+		for _, v := range values {
+			newText := v
+			if !quoted {
+				newText = strconv.Quote(v)
+			}
+
+			items = append(items, protocol.CompletionItem{
+				Label: v,
+				Kind:  protocol.CompletionItemKindEnumMember,
+				TextEdit: &protocol.TextEdit{
+					Range:   protocolRange(r),
+					NewText: newText,
+				},
+			})
+		}
+
+		return items, true
+	}
+
+	return nil, false
+}
+
 func formatMap(input map[string]string) string {
 	ans := "\n"
 
@@ -177,3 +259,114 @@ func formatMap(input map[string]string) string {
 
 	return ans
 }
+
+// CollectReferenceCompletions completes the partial reference, such as
+// `var.im` or `local.im`, ending at offset in src. References are completed
+// in attribute values and template interpolations, but not in string literals
+// or comments. The boolean result is false when there is no reference to
+// complete at offset.
+func CollectReferenceCompletions(src []byte, offset int, files map[string]*hcl.File) ([]protocol.CompletionItem, bool) {
+	root, _, ok := strings.Cut(referencePrefix(src, offset), ".")
+	if !ok || !inExpression(src, offset) {
+		return nil, false
+	}
+
+	items := []protocol.CompletionItem{}
+	seen := map[string]bool{}
+
+	switch root {
+	case "var":
+		for _, filename := range sortedFilenames(files) {
+			src := files[filename].Bytes
+
+			for _, v := range CollectVariables(files[filename].Body) {
+				if seen[v.Name] {
+					continue
+				}
+				seen[v.Name] = true
+
+				item := protocol.CompletionItem{
+					Label:  v.Name,
+					Kind:   protocol.CompletionItemKindVariable,
+					Detail: "any",
+				}
+
+				if v.Type != nil {
+					item.Detail = truncate(string(v.Type.Range().SliceBytes(src)))
+				}
+
+				if v.Description != nil {
+					if val, diags := v.Description.Value(nil); !diags.HasErrors() && val.Type() == cty.String && val.IsKnown() && !val.IsNull() {
+						item.Detail += " — " + val.AsString()
+					}
+				}
+
+				items = append(items, item)
+			}
+		}
+	case "local":
+		for _, filename := range sortedFilenames(files) {
+			for _, l := range CollectLocals(files[filename].Body) {
+				if seen[l.Name] {
+					continue
+				}
+				seen[l.Name] = true
+
+				items = append(items, protocol.CompletionItem{
+					Label:  l.Name,
+					Kind:   protocol.CompletionItemKindVariable,
+					Detail: truncate(string(l.Expr.Range().SliceBytes(files[filename].Bytes))),
+				})
+			}
+		}
+	default:
+		return nil, false
+	}
+
+	return items, true
+}
+
+// inExpression reports whether offset in src is part of an expression rather
+// than a string literal or a comment, using the tokens of src since the
+// expression being typed is usually not valid yet.
+func inExpression(src []byte, offset int) bool {
+	tokens, _ := hclsyntax.LexConfig(src, "", hcl.InitialPos)
+
+	for _, token := range tokens {
+		if token.Range.Start.Byte >= offset || token.Range.End.Byte < offset {
+			continue
+		}
+
+		switch token.Type {
+		case hclsyntax.TokenQuotedLit, hclsyntax.TokenStringLit, hclsyntax.TokenComment:
+			return false
+		}
+	}
+
+	return true
+}
+
+// referencePrefix returns the traversal, e.g. `local.im`, written directly
+// before offset in src. Only references up to the first attribute name are
+// recognised, deeper traversals result in an empty string.
+func referencePrefix(src []byte, offset int) string {
+	if offset > len(src) {
+		offset = len(src)
+	}
+
+	start := offset
+	for start > 0 {
+		c := src[start-1]
+		if c != '.' && c != '_' && c != '-' && !unicode.IsLetter(rune(c)) && !unicode.IsDigit(rune(c)) {
+			break
+		}
+		start--
+	}
+
+	prefix := string(src[start:offset])
+	if strings.Count(prefix, ".") != 1 {
+		return ""
+	}
+
+	return prefix
+}
@@ -0,0 +1,96 @@
+package lsp
+
+import (
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// CollectDefinitions returns the declarations of the `var.*` or `local.*`
+// reference under pos, or of the group volume named by the volume_mount
+// under pos. Declarations are looked up in files, which is expected to
+// contain the file being edited along with its siblings.
+func CollectDefinitions(body hcl.Body, pos hcl.Pos, files map[string]*hcl.File) []hcl.Range {
+	var ranges []hcl.Range
+
+	traversal := FindTraversal(body, pos)
+	if traversal == nil {
+		return CollectVolumeDefinitions(body, pos)
+	}
+
+	name, _, ok := traversalName(traversal)
+	if !ok {
+		return ranges
+	}
+
+	for _, filename := range sortedFilenames(files) {
+		switch traversal.RootName() {
+		case "var":
+			for _, v := range CollectVariables(files[filename].Body) {
+				if v.Name == name {
+					ranges = append(ranges, v.NameRange)
+				}
+			}
+		case "local":
+			for _, l := range CollectLocals(files[filename].Body) {
+				if l.Name == name {
+					ranges = append(ranges, l.NameRange)
+				}
+			}
+		}
+	}
+
+	return ranges
+}
+
+func sortedFilenames(files map[string]*hcl.File) []string {
+	filenames := make([]string, 0, len(files))
+
+	for filename, file := range files {
+		if file != nil {
+			filenames = append(filenames, filename)
+		}
+	}
+
+	sort.Strings(filenames)
+
+	return filenames
+}
+
+// scopedFiles returns the files of files, the siblings of filename, whose
+// declarations are in scope in filename. Siblings declaring a job of their own
+// are independent jobs and are left out of the scope of a job, while a file
+// without a job, such as a file of shared variables, sees all its siblings.
+func scopedFiles(filename string, files map[string]*hcl.File) map[string]*hcl.File {
+	file := files[filename]
+	if file == nil || !hasJob(file.Body) {
+		return files
+	}
+
+	scoped := map[string]*hcl.File{}
+
+	for name, sibling := range files {
+		if name == filename || (sibling != nil && !hasJob(sibling.Body)) {
+			scoped[name] = sibling
+		}
+	}
+
+	return scoped
+}
+
+// hasJob reports whether body declares a job.
+func hasJob(body hcl.Body) bool {
+	syntaxBody, ok := body.(*hclsyntax.Body)
+	if !ok {
+		return false
+	}
+
+	for _, b := range syntaxBody.Blocks {
+		if b.Type == "job" {
+			return true
+		}
+	}
+
+	return false
+}
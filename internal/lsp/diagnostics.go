@@ -1,20 +1,37 @@
 package lsp
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
 	hclschema "github.com/hashicorp/hcl-lang/schema"
 	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/loczek/nomad-ls/internal/rules"
 	"github.com/loczek/nomad-ls/internal/schema"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
 )
 
-func CollectDiagnostics(body hcl.Body) *hcl.Diagnostics {
+// CollectDiagnostics validates body against the job specification schema.
+// Expressions that decide the schema of a block, such as the `driver` of a
+// task, and attribute values are evaluated in ctx.
+func CollectDiagnostics(body hcl.Body, ctx *hcl.EvalContext) *hcl.Diagnostics {
 	var diags hcl.Diagnostics
 
-	diags = diags.Extend(CollectDiagnosticsDFS(body, &diags, &schema.RootBodySchema))
+	diags = diags.Extend(CollectDiagnosticsDFS(body, &diags, &schema.RootBodySchema, ctx))
+	diags = diags.Extend(CollectVariableDiagnostics(body))
+	diags = diags.Extend(CollectFunctionDiagnostics(body))
+	diags = diags.Extend(CollectRuntimeDiagnostics(body))
+	diags = diags.Extend(CollectPortDiagnostics(body))
+	diags = diags.Extend(CollectVolumeDiagnostics(body))
 
 	return &diags
 }
 
-func CollectDiagnosticsDFS(body hcl.Body, diags *hcl.Diagnostics, langSchema *hclschema.BodySchema) hcl.Diagnostics {
+func CollectDiagnosticsDFS(body hcl.Body, diags *hcl.Diagnostics, langSchema *hclschema.BodySchema, ctx *hcl.EvalContext) hcl.Diagnostics {
 	if langSchema == nil {
 		return make(hcl.Diagnostics, 0)
 	}
@@ -30,21 +47,211 @@ func CollectDiagnosticsDFS(body hcl.Body, diags *hcl.Diagnostics, langSchema *hc
 		bodyContent, allDiags = body.Content(langSchema.ToHCLSchema())
 	}
 
+	// unsupported, missing and duplicate attributes and blocks, as reported
+	// by hcl
+	for _, d := range allDiags {
+		if d.Extra == nil {
+			d.Extra = rules.DiagnosticExtra("schema")
+		}
+	}
+
+	for name, attr := range bodyContent.Attributes {
+		attrSchema := langSchema.Attributes[name]
+		if attrSchema == nil {
+			attrSchema = langSchema.AnyAttribute
+		}
+
+		allDiags = allDiags.Extend(checkAttributeType(attr, attrSchema, ctx))
+		allDiags = allDiags.Extend(checkAttributeFormat(attr, attrSchema, ctx))
+	}
+
 	blocksByType := bodyContent.Blocks.ByType()
 
 	for k, v := range blocksByType {
 		for _, b := range v {
 			if langSchema.Blocks[k] != nil && langSchema.Blocks[k].Body != nil {
-				allDiags = allDiags.Extend(CollectDiagnosticsDFS(b.Body, diags, langSchema.Blocks[k].Body))
+				allDiags = allDiags.Extend(CollectDiagnosticsDFS(b.Body, diags, langSchema.Blocks[k].Body, ctx))
 			} else if langSchema.Blocks[k] != nil && langSchema.Blocks[k].DependentBody != nil {
-				if bodyContent.Attributes["driver"] != nil {
-					driver, _ := bodyContent.Attributes["driver"].Expr.Value(&hcl.EvalContext{})
-
-					allDiags = allDiags.Extend(CollectDiagnosticsDFS(b.Body, diags, langSchema.Blocks[k].DependentBody[hclschema.SchemaKey(driver.AsString())]))
-				}
+				allDiags = allDiags.Extend(CollectDiagnosticsDFS(b.Body, diags, dependentBodySchema(langSchema.Blocks[k], bodyContent, ctx), ctx))
 			}
 		}
 	}
 
 	return allDiags
 }
+
+// checkAttributeType reports a value of attr that cannot be converted to the
+// literal type its schema expects, or that is not one of the values its
+// schema enumerates. Values that are not statically known, for example
+// because they depend on a variable without a default, are not checked.
+func checkAttributeType(attr *hcl.Attribute, attrSchema *hclschema.AttributeSchema, ctx *hcl.EvalContext) hcl.Diagnostics {
+	if attrSchema == nil {
+		return nil
+	}
+
+	ty, ok := constraintType(attrSchema.Constraint)
+	if !ok || ty == cty.DynamicPseudoType {
+		return nil
+	}
+
+	val, diags := attr.Expr.Value(ctx)
+	if diags.HasErrors() || val.IsNull() || !val.IsWhollyKnown() {
+		return nil
+	}
+
+	val, err := convert.Convert(val, ty)
+	if err != nil {
+		return hcl.Diagnostics{
+			{
+				Severity: hcl.DiagError,
+				Summary:  "Incorrect attribute value type",
+				Detail:   fmt.Sprintf("Inappropriate value for attribute %q: %s.", attr.Name, err),
+				Subject:  attr.Expr.Range().Ptr(),
+				Extra:    rules.DiagnosticExtra("attribute-type"),
+			},
+		}
+	}
+
+	values := enumValues(attrSchema.Constraint)
+	if len(values) == 0 || ty != cty.String || matchesEnumValue(val.AsString(), values) {
+		return nil
+	}
+
+	quoted := make([]string, 0, len(values))
+	for _, v := range values {
+		quoted = append(quoted, strconv.Quote(v))
+	}
+
+	detail := fmt.Sprintf("Invalid value for attribute %q, expected one of %s.", attr.Name, strings.Join(quoted, ", "))
+	if suggestion := closestName(val.AsString(), values); suggestion != "" {
+		detail += fmt.Sprintf(" Did you mean %q?", suggestion)
+	}
+
+	return hcl.Diagnostics{
+		{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid attribute value",
+			Detail:   detail,
+			Subject:  attr.Expr.Range().Ptr(),
+			Extra:    rules.DiagnosticExtra("attribute-value"),
+		},
+	}
+}
+
+// checkAttributeFormat reports a value of attr not following the format of its
+// schema, such as a malformed duration. Every element of a list is checked on
+// its own, and the diagnostics point at the offending part of string literals
+// when possible.
+func checkAttributeFormat(attr *hcl.Attribute, attrSchema *hclschema.AttributeSchema, ctx *hcl.EvalContext) hcl.Diagnostics {
+	format := schema.AttributeFormat(attrSchema)
+	if format == "" {
+		return nil
+	}
+
+	exprs := []hcl.Expression{attr.Expr}
+	if tuple, ok := attr.Expr.(*hclsyntax.TupleConsExpr); ok {
+		exprs = exprs[:0]
+		for _, e := range tuple.Exprs {
+			exprs = append(exprs, e)
+		}
+	}
+
+	var diags hcl.Diagnostics
+
+	for _, expr := range exprs {
+		val, valDiags := expr.Value(ctx)
+		if valDiags.HasErrors() {
+			continue
+		}
+
+		err := format.Validate(val)
+		if err == nil {
+			continue
+		}
+
+		subject := expr.Range()
+		if err.End > err.Start && isPlainStringLiteral(expr, val) {
+			subject.Start.Byte += 1 + err.Start
+			subject.Start.Column += 1 + err.Start
+			subject.End = subject.Start
+			subject.End.Byte += err.End - err.Start
+			subject.End.Column += err.End - err.Start
+		}
+
+		diags = diags.Append(&hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  err.Summary,
+			Detail:   err.Detail,
+			Subject:  subject.Ptr(),
+			Extra:    rules.DiagnosticExtra("attribute-format"),
+		})
+	}
+
+	return diags
+}
+
+// isPlainStringLiteral reports whether expr is a quoted string on a single
+// line whose source is exactly val, so that offsets within val map to
+// columns of the source.
+func isPlainStringLiteral(expr hcl.Expression, val cty.Value) bool {
+	template, ok := expr.(*hclsyntax.TemplateExpr)
+	if !ok || !template.IsStringLiteral() || val.Type() != cty.String {
+		return false
+	}
+
+	r := template.SrcRange
+	s := val.AsString()
+
+	return r.Start.Line == r.End.Line && r.End.Byte-r.Start.Byte == len(s)+2 && utf8.ValidString(s) && utf8.RuneCountInString(s) == len(s)
+}
+
+// constraintType returns the type of the values accepted by constraint when it
+// is a literal type or an enumeration of literal values.
+func constraintType(constraint hclschema.Constraint) (cty.Type, bool) {
+	switch c := constraint.(type) {
+	case *hclschema.LiteralType:
+		return c.Type, true
+	case hclschema.LiteralType:
+		return c.Type, true
+	case hclschema.OneOf:
+		if values := enumValues(c); len(values) > 0 {
+			return cty.String, true
+		}
+	}
+
+	return cty.NilType, false
+}
+
+// enumValues returns the strings enumerated by constraint, which is a OneOf
+// of string literal values, or nil for any other constraint.
+func enumValues(constraint hclschema.Constraint) []string {
+	oneOf, ok := constraint.(hclschema.OneOf)
+	if !ok {
+		return nil
+	}
+
+	var values []string
+
+	for _, c := range oneOf {
+		lv, ok := c.(hclschema.LiteralValue)
+		if !ok || lv.Value.Type() != cty.String {
+			return nil
+		}
+
+		values = append(values, lv.Value.AsString())
+	}
+
+	return values
+}
+
+// matchesEnumValue reports whether value is one of values. Values ending in a
+// slash, such as `cni/`, match any value they prefix.
+func matchesEnumValue(value string, values []string) bool {
+	for _, v := range values {
+		if value == v || (strings.HasSuffix(v, "/") && strings.HasPrefix(value, v) && len(value) > len(v)) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,146 @@
+package lsp
+
+import (
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// NewEvalContext returns the context used to statically evaluate expressions
+// of a job. `var.*` resolves to the values set in varFiles, falling back to
+// the defaults of the variables declared in files, converted to the declared
+// type of each variable. `local.*` resolves to the local values declared in
+// files, and the HCL2 functions are available, with the results of those
+// reading files or returning a different result on every call left unknown.
+// Variables without a statically known value, and local values that cannot be
+// evaluated or depend on themselves, are unknown.
+func NewEvalContext(files map[string]*hcl.File, varFiles []*hcl.File) *hcl.EvalContext {
+	values := map[string]cty.Value{}
+
+	for _, varFile := range varFiles {
+		attrs, _ := varFile.Body.JustAttributes()
+
+		for name, attr := range attrs {
+			if val, diags := attr.Expr.Value(nil); !diags.HasErrors() {
+				values[name] = val
+			}
+		}
+	}
+
+	vars := map[string]cty.Value{}
+
+	for _, filename := range sortedFilenames(files) {
+		for _, v := range CollectVariables(files[filename].Body) {
+			if _, ok := vars[v.Name]; ok {
+				continue
+			}
+
+			vars[v.Name] = variableValue(v, values)
+		}
+	}
+
+	ctx := &hcl.EvalContext{
+		Variables: map[string]cty.Value{
+			"var":   cty.ObjectVal(vars),
+			"local": cty.EmptyObjectVal,
+		},
+		Functions: evalFunctions,
+	}
+
+	evaluateLocals(ctx, collectLocalsByName(files))
+
+	return ctx
+}
+
+// variableValue returns the value of v, which is taken from values when set
+// there and is the default of v otherwise, converted to the type constraint of
+// v. The value is unknown when it is neither set nor has a valid default.
+func variableValue(v Variable, values map[string]cty.Value) cty.Value {
+	ty, defaults, diags := v.ConstraintType()
+	if diags.HasErrors() {
+		ty, defaults = cty.DynamicPseudoType, nil
+	}
+
+	val, ok := values[v.Name]
+	if !ok && v.Default != nil {
+		var valDiags hcl.Diagnostics
+		val, valDiags = v.Default.Value(nil)
+		ok = !valDiags.HasErrors()
+	}
+
+	if !ok {
+		return cty.UnknownVal(ty)
+	}
+
+	if defaults != nil {
+		val = defaults.Apply(val)
+	}
+
+	converted, err := convert.Convert(val, ty)
+	if err != nil {
+		return cty.UnknownVal(ty)
+	}
+
+	return converted
+}
+
+// evaluateLocals evaluates locals in dependency order, adding each value to
+// the `local` object of ctx as soon as the local values it refers to are
+// known. Local values left over once no progress can be made are part of a
+// cycle and are unknown.
+func evaluateLocals(ctx *hcl.EvalContext, locals map[string]Local) {
+	names := make([]string, 0, len(locals))
+	for name := range locals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	values := map[string]cty.Value{}
+
+	for progress := true; progress; {
+		progress = false
+
+		for _, name := range names {
+			if _, ok := values[name]; ok || !localDependenciesEvaluated(locals[name], locals, values) {
+				continue
+			}
+
+			val, diags := locals[name].Expr.Value(ctx)
+			if diags.HasErrors() {
+				val = cty.DynamicVal
+			}
+
+			values[name] = val
+			ctx.Variables["local"] = cty.ObjectVal(values)
+			progress = true
+		}
+	}
+
+	for _, name := range names {
+		if _, ok := values[name]; !ok {
+			values[name] = cty.DynamicVal
+		}
+	}
+
+	if len(values) > 0 {
+		ctx.Variables["local"] = cty.ObjectVal(values)
+	}
+}
+
+// localDependenciesEvaluated reports whether every declared local value that
+// l refers to has already been evaluated.
+func localDependenciesEvaluated(l Local, locals map[string]Local, values map[string]cty.Value) bool {
+	for _, dep := range localDependencies(l.Expr) {
+		if _, declared := locals[dep]; !declared {
+			continue
+		}
+
+		if _, ok := values[dep]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
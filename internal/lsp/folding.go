@@ -0,0 +1,60 @@
+package lsp
+
+import (
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"go.lsp.dev/protocol"
+)
+
+// CollectFoldingRanges returns the folding ranges of every block body,
+// heredoc and multi-line list or map in body. The line holding the closing
+// brace, bracket or heredoc marker is left unfolded.
+func CollectFoldingRanges(body hcl.Body) []protocol.FoldingRange {
+	ranges := []protocol.FoldingRange{}
+
+	syntaxBody, ok := body.(*hclsyntax.Body)
+	if !ok {
+		return ranges
+	}
+
+	CollectFoldingRangesDFS(syntaxBody, &ranges)
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].StartLine < ranges[j].StartLine
+	})
+
+	return ranges
+}
+
+func CollectFoldingRangesDFS(body *hclsyntax.Body, ranges *[]protocol.FoldingRange) {
+	for _, attr := range body.Attributes {
+		hclsyntax.VisitAll(attr.Expr, func(node hclsyntax.Node) hcl.Diagnostics {
+			switch n := node.(type) {
+			case *hclsyntax.TupleConsExpr, *hclsyntax.ObjectConsExpr, *hclsyntax.TemplateExpr:
+				appendFoldingRange(ranges, n.Range().Start.Line, n.Range().End.Line)
+			}
+			return nil
+		})
+	}
+
+	for _, b := range body.Blocks {
+		appendFoldingRange(ranges, b.TypeRange.Start.Line, b.Body.SrcRange.End.Line)
+
+		CollectFoldingRangesDFS(b.Body, ranges)
+	}
+}
+
+// appendFoldingRange appends a range folding the lines after startLine up to
+// the line before endLine, both of which are 1-based.
+func appendFoldingRange(ranges *[]protocol.FoldingRange, startLine int, endLine int) {
+	if endLine-1 <= startLine {
+		return
+	}
+
+	*ranges = append(*ranges, protocol.FoldingRange{
+		StartLine: uint32(startLine - 1),
+		EndLine:   uint32(endLine - 2),
+	})
+}
@@ -0,0 +1,692 @@
+package lsp
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hashicorp/go-cty-funcs/cidr"
+	"github.com/hashicorp/go-cty-funcs/crypto"
+	"github.com/hashicorp/go-cty-funcs/filesystem"
+	"github.com/hashicorp/go-cty-funcs/uuid"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/tryfunc"
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/loczek/nomad-ls/internal/rules"
+	ctyyaml "github.com/zclconf/go-cty-yaml"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+	"go.lsp.dev/protocol"
+)
+
+// functions is the catalog of functions used for completions, signature help,
+// hover and diagnostics.
+var functions = Functions("")
+
+// evalFunctions are the functions of eval contexts, in which the functions
+// reading the disk or returning a different result on every call are stubs
+// returning unknown values, so that evaluating a job stays cheap and
+// deterministic.
+var evalFunctions = withStubs(functions, "abspath", "bcrypt", "file", "fileexists", "fileset", "templatefile", "uuidv4")
+
+// Functions returns the HCL2 functions available in job specifications. The
+// file functions resolve relative paths against baseDir, which is the
+// directory of the job file.
+func Functions(baseDir string) map[string]function.Function {
+	return map[string]function.Function{
+		"abs":             stdlib.AbsoluteFunc,
+		"abspath":         filesystem.AbsPathFunc.WithNewDescriptions("Converts a path to an absolute path, resolved against the current working directory.", []string{""}),
+		"base64decode":    base64DecodeFunc,
+		"base64encode":    base64EncodeFunc,
+		"basename":        filesystem.BasenameFunc.WithNewDescriptions("Returns the last element of a filesystem path.", []string{""}),
+		"bcrypt":          crypto.BcryptFunc.WithNewDescriptions("Computes a hash of the given string using the Blowfish cipher, with an optional cost defaulting to 10.", []string{"", ""}),
+		"can":             tryfunc.CanFunc.WithNewDescriptions("Evaluates the given expression and returns a boolean value indicating whether the expression produced a result without any errors.", []string{""}),
+		"ceil":            stdlib.CeilFunc,
+		"chomp":           stdlib.ChompFunc,
+		"chunklist":       stdlib.ChunklistFunc,
+		"cidrhost":        cidr.HostFunc.WithNewDescriptions("Calculates a full host IP address for a given host number within a given IP network address prefix.", []string{"", ""}),
+		"cidrnetmask":     cidr.NetmaskFunc.WithNewDescriptions("Converts an IPv4 address prefix given in CIDR notation into a subnet mask address.", []string{""}),
+		"cidrsubnet":      cidr.SubnetFunc.WithNewDescriptions("Calculates a subnet address within a given IP network address prefix.", []string{"", "", ""}),
+		"cidrsubnets":     cidr.SubnetsFunc.WithNewDescriptions("Calculates a sequence of consecutive IP address ranges within a particular CIDR prefix.", []string{"", ""}),
+		"coalesce":        stdlib.CoalesceFunc,
+		"coalescelist":    stdlib.CoalesceListFunc,
+		"compact":         stdlib.CompactFunc,
+		"concat":          stdlib.ConcatFunc,
+		"contains":        stdlib.ContainsFunc,
+		"convert":         typeexpr.ConvertFunc.WithNewDescriptions("Converts a value to the given type constraint, e.g. `convert(var.ports, list(number))`.", []string{"", ""}),
+		"csvdecode":       stdlib.CSVDecodeFunc,
+		"dirname":         filesystem.DirnameFunc.WithNewDescriptions("Returns all but the last element of a filesystem path.", []string{""}),
+		"distinct":        stdlib.DistinctFunc,
+		"element":         stdlib.ElementFunc,
+		"file":            makeFileFunc(baseDir),
+		"fileexists":      makeFileExistsFunc(baseDir),
+		"fileset":         filesystem.MakeFileSetFunc(baseDir).WithNewDescriptions("Enumerates the files in the given path that match the given pattern. Relative paths are resolved against the directory of the job file.", []string{"", ""}),
+		"flatten":         stdlib.FlattenFunc,
+		"floor":           stdlib.FloorFunc,
+		"format":          stdlib.FormatFunc,
+		"formatdate":      stdlib.FormatDateFunc,
+		"formatlist":      stdlib.FormatListFunc,
+		"indent":          stdlib.IndentFunc,
+		"index":           stdlib.IndexFunc,
+		"join":            stdlib.JoinFunc,
+		"jsondecode":      stdlib.JSONDecodeFunc,
+		"jsonencode":      stdlib.JSONEncodeFunc,
+		"keys":            stdlib.KeysFunc,
+		"length":          stdlib.LengthFunc,
+		"log":             stdlib.LogFunc,
+		"lookup":          stdlib.LookupFunc,
+		"lower":           stdlib.LowerFunc,
+		"max":             stdlib.MaxFunc,
+		"md5":             crypto.Md5Func.WithNewDescriptions("Computes the MD5 hash of the given string and encodes it with hexadecimal digits.", []string{""}),
+		"merge":           stdlib.MergeFunc,
+		"min":             stdlib.MinFunc,
+		"parseint":        stdlib.ParseIntFunc,
+		"pathexpand":      filesystem.PathExpandFunc.WithNewDescriptions("Replaces a leading `~` in a filesystem path with the home directory of the current user.", []string{""}),
+		"pow":             stdlib.PowFunc,
+		"range":           stdlib.RangeFunc,
+		"regex":           stdlib.RegexFunc,
+		"regex_replace":   stdlib.RegexReplaceFunc,
+		"regexall":        stdlib.RegexAllFunc,
+		"replace":         stdlib.ReplaceFunc,
+		"reverse":         stdlib.ReverseListFunc,
+		"rsadecrypt":      crypto.RsaDecryptFunc.WithNewDescriptions("Decrypts an RSA-encrypted ciphertext, encoded with base64, with the given PEM-encoded private key.", []string{"", ""}),
+		"setintersection": stdlib.SetIntersectionFunc,
+		"setproduct":      stdlib.SetProductFunc,
+		"setsubtract":     stdlib.SetSubtractFunc,
+		"setunion":        stdlib.SetUnionFunc,
+		"sha1":            crypto.Sha1Func.WithNewDescriptions("Computes the SHA1 hash of the given string and encodes it with hexadecimal digits.", []string{""}),
+		"sha256":          crypto.Sha256Func.WithNewDescriptions("Computes the SHA256 hash of the given string and encodes it with hexadecimal digits.", []string{""}),
+		"sha512":          crypto.Sha512Func.WithNewDescriptions("Computes the SHA512 hash of the given string and encodes it with hexadecimal digits.", []string{""}),
+		"signum":          stdlib.SignumFunc,
+		"slice":           stdlib.SliceFunc,
+		"sort":            stdlib.SortFunc,
+		"split":           stdlib.SplitFunc,
+		"strlen":          stdlib.StrlenFunc,
+		"strrev":          stdlib.ReverseFunc,
+		"substr":          stdlib.SubstrFunc,
+		"templatefile":    makeTemplateFileFunc(baseDir),
+		"timeadd":         stdlib.TimeAddFunc,
+		"title":           stdlib.TitleFunc,
+		"tobool":          stdlib.MakeToFunc(cty.Bool),
+		"tolist":          stdlib.MakeToFunc(cty.List(cty.DynamicPseudoType)),
+		"tomap":           stdlib.MakeToFunc(cty.Map(cty.DynamicPseudoType)),
+		"tonumber":        stdlib.MakeToFunc(cty.Number),
+		"toset":           stdlib.MakeToFunc(cty.Set(cty.DynamicPseudoType)),
+		"tostring":        stdlib.MakeToFunc(cty.String),
+		"trim":            stdlib.TrimFunc,
+		"trimprefix":      stdlib.TrimPrefixFunc,
+		"trimspace":       stdlib.TrimSpaceFunc,
+		"trimsuffix":      stdlib.TrimSuffixFunc,
+		"try":             tryfunc.TryFunc.WithNewDescriptions("Evaluates all of its argument expressions in turn and returns the result of the first one that does not produce any errors.", []string{}),
+		"upper":           stdlib.UpperFunc,
+		"urlencode":       urlEncodeFunc,
+		"uuidv4":          uuid.V4Func.WithNewDescriptions("Generates a new random UUID.", []string{}),
+		"uuidv5":          uuid.V5Func.WithNewDescriptions("Generates a name-based UUID within the given namespace, either `dns`, `url`, `oid`, `x500` or a UUID.", []string{"", ""}),
+		"values":          stdlib.ValuesFunc,
+		"yamldecode":      ctyyaml.YAMLDecodeFunc.WithNewDescriptions("Parses a string as a subset of YAML, and produces a representation of its value.", []string{""}),
+		"yamlencode":      ctyyaml.YAMLEncodeFunc.WithNewDescriptions("Encodes a given value to a string using YAML 1.2 block syntax.", []string{""}),
+		"zipmap":          stdlib.ZipmapFunc,
+	}
+}
+
+// withStubs returns a copy of funcs where the functions named by names are
+// replaced by stubs.
+func withStubs(funcs map[string]function.Function, names ...string) map[string]function.Function {
+	stubbed := make(map[string]function.Function, len(funcs))
+	for name, f := range funcs {
+		stubbed[name] = f
+	}
+
+	for _, name := range names {
+		stubbed[name] = stubFunction(funcs[name])
+	}
+
+	return stubbed
+}
+
+// stubFunction returns a function with the signature and return type of f,
+// whose result is always unknown.
+func stubFunction(f function.Function) function.Function {
+	return function.New(&function.Spec{
+		Description: f.Description(),
+		Params:      f.Params(),
+		VarParam:    f.VarParam(),
+		Type:        f.ReturnTypeForValues,
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			return cty.UnknownVal(retType), nil
+		},
+	})
+}
+
+var base64DecodeFunc = function.New(&function.Spec{
+	Description: "Decodes a string containing a base64 sequence.",
+	Params: []function.Parameter{
+		{Name: "str", Type: cty.String},
+	},
+	Type: function.StaticReturnType(cty.String),
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		decoded, err := base64.StdEncoding.DecodeString(args[0].AsString())
+		if err != nil {
+			return cty.UnknownVal(cty.String), fmt.Errorf("failed to decode base64 data: %w", err)
+		}
+
+		if !utf8.Valid(decoded) {
+			return cty.UnknownVal(cty.String), fmt.Errorf("the result of decoding the provided string is not valid UTF-8")
+		}
+
+		return cty.StringVal(string(decoded)), nil
+	},
+})
+
+var base64EncodeFunc = function.New(&function.Spec{
+	Description: "Applies base64 encoding to a string.",
+	Params: []function.Parameter{
+		{Name: "str", Type: cty.String},
+	},
+	Type: function.StaticReturnType(cty.String),
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		return cty.StringVal(base64.StdEncoding.EncodeToString([]byte(args[0].AsString()))), nil
+	},
+})
+
+var urlEncodeFunc = function.New(&function.Spec{
+	Description: "Applies URL encoding to a given string.",
+	Params: []function.Parameter{
+		{Name: "str", Type: cty.String},
+	},
+	Type: function.StaticReturnType(cty.String),
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		return cty.StringVal(url.QueryEscape(args[0].AsString())), nil
+	},
+})
+
+func makeFileFunc(baseDir string) function.Function {
+	return function.New(&function.Spec{
+		Description: "Reads the contents of a file at the given path and returns them as a string. Relative paths are resolved against the directory of the job file.",
+		Params: []function.Parameter{
+			{Name: "path", Type: cty.String},
+		},
+		Type: function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			src, err := os.ReadFile(resolvePath(baseDir, args[0].AsString()))
+			if err != nil {
+				return cty.UnknownVal(cty.String), err
+			}
+
+			if !utf8.Valid(src) {
+				return cty.UnknownVal(cty.String), fmt.Errorf("contents of %s are not valid UTF-8", args[0].AsString())
+			}
+
+			return cty.StringVal(string(src)), nil
+		},
+	})
+}
+
+func makeFileExistsFunc(baseDir string) function.Function {
+	return function.New(&function.Spec{
+		Description: "Determines whether a file exists at the given path. Relative paths are resolved against the directory of the job file.",
+		Params: []function.Parameter{
+			{Name: "path", Type: cty.String},
+		},
+		Type: function.StaticReturnType(cty.Bool),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			info, err := os.Stat(resolvePath(baseDir, args[0].AsString()))
+			if err != nil {
+				return cty.False, nil
+			}
+
+			return cty.BoolVal(info.Mode().IsRegular()), nil
+		},
+	})
+}
+
+func makeTemplateFileFunc(baseDir string) function.Function {
+	return function.New(&function.Spec{
+		Description: "Reads the file at the given path and renders its content as a template using the supplied set of template variables.",
+		Params: []function.Parameter{
+			{Name: "path", Type: cty.String},
+			{Name: "vars", Type: cty.DynamicPseudoType},
+		},
+		Type: function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			filename := resolvePath(baseDir, args[0].AsString())
+
+			src, err := os.ReadFile(filename)
+			if err != nil {
+				return cty.UnknownVal(cty.String), err
+			}
+
+			expr, diags := hclsyntax.ParseTemplate(src, filename, hcl.InitialPos)
+			if diags.HasErrors() {
+				return cty.UnknownVal(cty.String), diags
+			}
+
+			vars := args[1]
+			if !vars.IsWhollyKnown() {
+				return cty.UnknownVal(cty.String), nil
+			}
+
+			if !vars.Type().IsObjectType() && !vars.Type().IsMapType() {
+				return cty.UnknownVal(cty.String), fmt.Errorf("invalid vars value: must be a map")
+			}
+
+			ctx := &hcl.EvalContext{Variables: map[string]cty.Value{}}
+			for k, v := range vars.AsValueMap() {
+				ctx.Variables[k] = v
+			}
+
+			val, diags := expr.Value(ctx)
+			if diags.HasErrors() {
+				return cty.UnknownVal(cty.String), diags
+			}
+
+			return val, nil
+		},
+	})
+}
+
+func resolvePath(baseDir string, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(baseDir, path)
+}
+
+// functionSignature renders the signature of a function, e.g.
+// `join(separator string, lists list of string...)`, along with the ranges of
+// each parameter name and type within it.
+func functionSignature(name string, f function.Function) (string, []protocol.ParameterInformation) {
+	var sb strings.Builder
+	var params []protocol.ParameterInformation
+
+	sb.WriteString(name + "(")
+
+	appendParam := func(p function.Parameter, variadic bool) {
+		if len(params) > 0 {
+			sb.WriteString(", ")
+		}
+
+		label := p.Name + " " + friendlyTypeName(p.Type)
+		if variadic {
+			label += "..."
+		}
+
+		sb.WriteString(label)
+
+		params = append(params, protocol.ParameterInformation{
+			Label:         label,
+			Documentation: p.Description,
+		})
+	}
+
+	for _, p := range f.Params() {
+		appendParam(p, false)
+	}
+
+	if p := f.VarParam(); p != nil {
+		appendParam(*p, true)
+	}
+
+	sb.WriteString(")")
+
+	return sb.String(), params
+}
+
+func friendlyTypeName(t cty.Type) string {
+	if t == cty.DynamicPseudoType {
+		return "any"
+	}
+
+	return t.FriendlyNameForConstraint()
+}
+
+// functionNames returns the names of the functions in the catalog, sorted.
+func functionNames(funcs map[string]function.Function) []string {
+	names := make([]string, 0, len(funcs))
+	for name := range funcs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// CollectFunctionCompletions returns the functions whose name starts with the
+// partial identifier ending at offset in src, when offset is in a position
+// where a value is expected. The boolean result is false otherwise.
+func CollectFunctionCompletions(src []byte, offset int) ([]protocol.CompletionItem, bool) {
+	prefix := identifierPrefix(src, offset)
+	if prefix == "" || !inExpression(src, offset) || !inValuePosition(src, offset-len(prefix)) {
+		return nil, false
+	}
+
+	funcs := functions
+
+	items := []protocol.CompletionItem{}
+
+	for _, name := range functionNames(funcs) {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		signature, _ := functionSignature(name, funcs[name])
+
+		items = append(items, protocol.CompletionItem{
+			Label:  name,
+			Kind:   protocol.CompletionItemKindFunction,
+			Detail: signature,
+			Documentation: protocol.MarkupContent{
+				Kind:  protocol.Markdown,
+				Value: funcs[name].Description(),
+			},
+			InsertText:       name + "($0)",
+			InsertTextFormat: protocol.InsertTextFormatSnippet,
+		})
+	}
+
+	return items, true
+}
+
+// identifierPrefix returns the identifier, not preceded by a dot, written
+// directly before offset in src.
+func identifierPrefix(src []byte, offset int) string {
+	prefix := referencePrefix(src, offset)
+	if prefix != "" {
+		return ""
+	}
+
+	if offset > len(src) {
+		offset = len(src)
+	}
+
+	start := offset
+	for start > 0 && isIdentifierByte(src[start-1]) {
+		start--
+	}
+
+	if start > 0 && src[start-1] == '.' {
+		return ""
+	}
+
+	return string(src[start:offset])
+}
+
+// inValuePosition reports whether the text before offset in src ends with a
+// token after which a value is expected, such as `=`, `(`, `,` or `${`.
+func inValuePosition(src []byte, offset int) bool {
+	before := strings.TrimRight(string(src[:offset]), " \t")
+
+	if strings.HasSuffix(before, "${") {
+		return true
+	}
+
+	if strings.HasSuffix(before, "\n") {
+		before = strings.TrimRight(before, " \t\r\n")
+		return strings.HasSuffix(before, ",") || strings.HasSuffix(before, "(") || strings.HasSuffix(before, "[")
+	}
+
+	if before == "" {
+		return false
+	}
+
+	return strings.ContainsRune("=(,[?:+-*/%!<>&|", rune(before[len(before)-1]))
+}
+
+func isIdentifierByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// CollectSignatureHelp returns the signature of the function whose call
+// arguments contain offset in src, with the argument under offset as the
+// active parameter. The lexer is used instead of the syntax tree since the
+// call being typed is usually not valid yet.
+func CollectSignatureHelp(src []byte, offset int) *protocol.SignatureHelp {
+	tokens, _ := hclsyntax.LexConfig(src, "", hcl.InitialPos)
+
+	end := 0
+	for end < len(tokens) && tokens[end].Range.End.Byte <= offset {
+		end++
+	}
+
+	depth := 0
+	activeParameter := uint32(0)
+
+	for i := end - 1; i >= 0; i-- {
+		switch tokens[i].Type {
+		case hclsyntax.TokenCParen, hclsyntax.TokenCBrack, hclsyntax.TokenCBrace, hclsyntax.TokenTemplateSeqEnd:
+			depth++
+		case hclsyntax.TokenOBrack, hclsyntax.TokenTemplateInterp, hclsyntax.TokenTemplateControl:
+			if depth > 0 {
+				depth--
+				continue
+			}
+
+			// offset is inside a tuple or an interpolation, which may
+			// itself be an argument of the call
+			activeParameter = 0
+		case hclsyntax.TokenOBrace:
+			if depth > 0 {
+				depth--
+				continue
+			}
+
+			// a brace following a value separator opens an object, any
+			// other opens the body of the enclosing block
+			if i == 0 || !isValueSeparator(tokens[i-1].Type) {
+				return nil
+			}
+
+			activeParameter = 0
+		case hclsyntax.TokenComma:
+			if depth == 0 {
+				activeParameter++
+			}
+		case hclsyntax.TokenOParen:
+			if depth > 0 {
+				depth--
+				continue
+			}
+
+			if i == 0 || tokens[i-1].Type != hclsyntax.TokenIdent {
+				activeParameter = 0
+				continue
+			}
+
+			name := string(tokens[i-1].Bytes)
+
+			f, ok := functions[name]
+			if !ok {
+				return nil
+			}
+
+			signature, params := functionSignature(name, f)
+
+			if f.VarParam() != nil && int(activeParameter) >= len(params) {
+				activeParameter = uint32(len(params) - 1)
+			}
+
+			return &protocol.SignatureHelp{
+				Signatures: []protocol.SignatureInformation{
+					{
+						Label: signature,
+						Documentation: protocol.MarkupContent{
+							Kind:  protocol.Markdown,
+							Value: f.Description(),
+						},
+						Parameters: params,
+					},
+				},
+				ActiveParameter: activeParameter,
+			}
+		}
+	}
+
+	return nil
+}
+
+func isValueSeparator(tokenType hclsyntax.TokenType) bool {
+	switch tokenType {
+	case hclsyntax.TokenEqual, hclsyntax.TokenOParen, hclsyntax.TokenOBrack, hclsyntax.TokenComma, hclsyntax.TokenColon, hclsyntax.TokenTemplateInterp:
+		return true
+	}
+
+	return false
+}
+
+// CollectFunctionHover describes the function whose name is under pos, or
+// returns an empty string when pos is not on a function call.
+func CollectFunctionHover(body hcl.Body, pos hcl.Pos) string {
+	syntaxBody, ok := body.(*hclsyntax.Body)
+	if !ok {
+		return ""
+	}
+
+	ans := ""
+
+	hclsyntax.VisitAll(syntaxBody, func(node hclsyntax.Node) hcl.Diagnostics {
+		call, ok := node.(*hclsyntax.FunctionCallExpr)
+		if !ok || !call.NameRange.ContainsPos(pos) {
+			return nil
+		}
+
+		if f, ok := functions[call.Name]; ok {
+			signature, _ := functionSignature(call.Name, f)
+			ans = fmt.Sprintf("```hcl\n%s\n```\n\n%s", signature, f.Description())
+		}
+
+		return nil
+	})
+
+	return ans
+}
+
+// CollectFunctionDiagnostics reports calls to functions that are not part of
+// the catalog and calls with the wrong number of arguments.
+func CollectFunctionDiagnostics(body hcl.Body) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+
+	syntaxBody, ok := body.(*hclsyntax.Body)
+	if !ok {
+		return diags
+	}
+
+	funcs := functions
+
+	// type expressions such as `list(string)` are parsed as function calls
+	var typeRanges []hcl.Range
+	for _, v := range CollectVariables(body) {
+		if v.Type != nil {
+			typeRanges = append(typeRanges, v.Type.Range())
+		}
+	}
+
+	typeRanges = append(typeRanges, typeArgumentRanges(syntaxBody)...)
+
+	hclsyntax.VisitAll(syntaxBody, func(node hclsyntax.Node) hcl.Diagnostics {
+		call, ok := node.(*hclsyntax.FunctionCallExpr)
+		if !ok || containsRange(typeRanges, call.Range()) {
+			return nil
+		}
+
+		f, ok := funcs[call.Name]
+		if !ok {
+			detail := fmt.Sprintf("There is no function named %q.", call.Name)
+			if suggestion := closestName(call.Name, functionNames(funcs)); suggestion != "" {
+				detail += fmt.Sprintf(" Did you mean %q?", suggestion)
+			}
+
+			// the catalog may lag behind nomad, which has the final say
+			diags = diags.Append(&hcl.Diagnostic{
+				Severity: hcl.DiagWarning,
+				Summary:  "Call to unknown function",
+				Detail:   detail,
+				Subject:  call.NameRange.Ptr(),
+				Extra:    rules.DiagnosticExtra("unknown-function"),
+			})
+
+			return nil
+		}
+
+		params := f.Params()
+
+		// the final argument expands to an unknown number of arguments
+		if call.ExpandFinal {
+			return nil
+		}
+
+		switch {
+		case len(call.Args) < len(params):
+			missing := params[len(call.Args)]
+
+			diags = diags.Append(&hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Not enough function arguments",
+				Detail:   fmt.Sprintf("Function %q expects %d argument(s). Missing value for %q.", call.Name, len(params), missing.Name),
+				Subject:  call.CloseParenRange.Ptr(),
+				Extra:    rules.DiagnosticExtra("function-arguments"),
+			})
+		case len(call.Args) > len(params) && f.VarParam() == nil:
+			diags = diags.Append(&hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Too many function arguments",
+				Detail:   fmt.Sprintf("Function %q expects only %d argument(s).", call.Name, len(params)),
+				Subject:  call.Args[len(params)].Range().Ptr(),
+				Extra:    rules.DiagnosticExtra("function-arguments"),
+			})
+		}
+
+		return nil
+	})
+
+	return diags
+}
+
+// typeArgumentRanges returns the ranges of the arguments of the function calls
+// in node whose parameter is a type constraint, e.g. `list(string)` in
+// `convert(var.tags, list(string))`.
+func typeArgumentRanges(node hclsyntax.Node) []hcl.Range {
+	var ranges []hcl.Range
+
+	funcs := functions
+
+	hclsyntax.VisitAll(node, func(node hclsyntax.Node) hcl.Diagnostics {
+		call, ok := node.(*hclsyntax.FunctionCallExpr)
+		if !ok {
+			return nil
+		}
+
+		f, ok := funcs[call.Name]
+		if !ok {
+			return nil
+		}
+
+		for i, param := range f.Params() {
+			if i < len(call.Args) && param.Type.Equals(typeexpr.TypeConstraintType) {
+				ranges = append(ranges, call.Args[i].Range())
+			}
+		}
+
+		return nil
+	})
+
+	return ranges
+}
+
+// containsRange reports whether r lies within any of ranges.
+func containsRange(ranges []hcl.Range, r hcl.Range) bool {
+	for _, outer := range ranges {
+		if outer.Filename == r.Filename && outer.Start.Byte <= r.Start.Byte && r.End.Byte <= outer.End.Byte {
+			return true
+		}
+	}
+
+	return false
+}
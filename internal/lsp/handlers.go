@@ -3,63 +3,203 @@ package lsp
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"path/filepath"
 	"runtime/debug"
+	"strings"
 
 	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/loczek/nomad-ls/internal/parser"
+	"github.com/loczek/nomad-ls/internal/rules"
 	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
 )
 
-func (s *Service) HandleInitialize(ctx context.Context, params *protocol.InitializedParams) (*protocol.InitializeResult, error) {
+func (s *Service) HandleInitialize(ctx context.Context, params *protocol.InitializeParams) (*InitializeResult, error) {
 	info, ok := debug.ReadBuildInfo()
 	if !ok {
 		return nil, errors.New("could not read build info")
 	}
 
-	return &protocol.InitializeResult{
+	s.workspaceFolders = workspaceFolders(params)
+	s.configure(params)
+
+	if workspace := params.Capabilities.Workspace; workspace != nil && workspace.DidChangeWatchedFiles != nil {
+		s.watchFiles = workspace.DidChangeWatchedFiles.DynamicRegistration
+	}
+
+	go s.parser.IndexWorkspace(s.workspaceFolders)
+
+	return &InitializeResult{
 		ServerInfo: &protocol.ServerInfo{
 			Name:    "nomad-ls",
 			Version: info.Main.Version,
 		},
-		Capabilities: protocol.ServerCapabilities{
-			CompletionProvider: &protocol.CompletionOptions{},
-			HoverProvider:      &protocol.HoverOptions{},
-			TextDocumentSync: &protocol.TextDocumentSyncOptions{
-				Change: protocol.TextDocumentSyncKindFull,
+		Capabilities: ServerCapabilities{
+			InlayHintProvider: true,
+			ServerCapabilities: protocol.ServerCapabilities{
+				CompletionProvider: &protocol.CompletionOptions{},
+				HoverProvider:      &protocol.HoverOptions{},
+				DefinitionProvider: &protocol.DefinitionOptions{},
+				ReferencesProvider: &protocol.ReferenceOptions{},
+				RenameProvider: &protocol.RenameOptions{
+					PrepareProvider: true,
+				},
+				TextDocumentSync: &protocol.TextDocumentSyncOptions{
+					Change: protocol.TextDocumentSyncKindFull,
+				},
+				DocumentFormattingProvider: &protocol.DocumentFormattingOptions{},
+				DocumentSymbolProvider:     &protocol.DocumentSymbolOptions{},
+				FoldingRangeProvider:       &protocol.FoldingRangeOptions{},
+				CodeActionProvider: &protocol.CodeActionOptions{
+					CodeActionKinds: []protocol.CodeActionKind{protocol.QuickFix},
+				},
+				WorkspaceSymbolProvider: &protocol.WorkspaceSymbolOptions{},
+				ExecuteCommandProvider: &protocol.ExecuteCommandOptions{
+					Commands: []string{CommandSelectVarFiles},
+				},
+				SignatureHelpProvider: &protocol.SignatureHelpOptions{
+					TriggerCharacters: []string{"(", ","},
+				},
+				SemanticTokensProvider: &semanticTokensOptions{
+					Legend: protocol.SemanticTokensLegend{
+						TokenTypes:     semanticTokenTypes,
+						TokenModifiers: semanticTokenModifiers,
+					},
+					Range: true,
+					Full:  true,
+				},
 			},
-			DocumentFormattingProvider: &protocol.DocumentFormattingOptions{},
 		},
 	}, nil
 }
 
+// HandleInitialized loads the project configuration, which is done once the
+// client is initialized so that its diagnostics can be published, and
+// registers a watcher for the configuration files when the client supports it.
+func (s *Service) HandleInitialized(ctx context.Context) error {
+	s.publishConfigDiagnostics(s.loadConfig())
+
+	if !s.watchFiles {
+		return nil
+	}
+
+	var watchers []protocol.FileSystemWatcher
+	for _, name := range rules.ConfigFileNames {
+		watchers = append(watchers, protocol.FileSystemWatcher{GlobPattern: "**/" + name})
+	}
+
+	_, err := s.con.Call(ctx, protocol.MethodClientRegisterCapability, protocol.RegistrationParams{
+		Registrations: []protocol.Registration{
+			{
+				ID:     "nomad-ls-config",
+				Method: protocol.MethodWorkspaceDidChangeWatchedFiles,
+				RegisterOptions: protocol.DidChangeWatchedFilesRegistrationOptions{
+					Watchers: watchers,
+				},
+			},
+		},
+	}, nil)
+
+	return err
+}
+
+// HandleWorkspaceDidChangeWatchedFiles reloads the project configuration when
+// one of its files changed, then publishes the diagnostics of the open files
+// again.
+func (s *Service) HandleWorkspaceDidChangeWatchedFiles(ctx context.Context, params *protocol.DidChangeWatchedFilesParams) error {
+	changed := false
+
+	for _, change := range params.Changes {
+		if s.isConfigFile(change.URI.Filename()) {
+			changed = true
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+
+	s.publishConfigDiagnostics(s.loadConfig())
+	s.republishDiagnostics()
+
+	return nil
+}
+
+// publishConfigDiagnostics publishes the diagnostics of the configuration
+// file filename. The diagnostics of the other configuration files are cleared,
+// as they may have been removed or replaced.
+func (s *Service) publishConfigDiagnostics(filename string, diags hcl.Diagnostics) {
+	if len(s.workspaceFolders) == 0 {
+		return
+	}
+
+	for _, name := range rules.ConfigFileNames {
+		configFilename := filepath.Join(s.workspaceFolders[0], name)
+
+		if configFilename == filename {
+			s.publishDiagnostics(uri.File(configFilename), 0, diags)
+		} else {
+			s.publishDiagnostics(uri.File(configFilename), 0, nil)
+		}
+	}
+}
+
+// republishDiagnostics publishes the diagnostics of every open file again,
+// after a change affecting all of them.
+func (s *Service) republishDiagnostics() {
+	for filename, file := range s.parser.Files() {
+		file, diags := s.parser.UpdateHCL(file.Bytes, filename)
+
+		s.publishDiagnostics(uri.File(filename), 0, diags.Extend(s.collectDiagnostics(filename, file)))
+	}
+}
+
 func (s *Service) HandleTextDocumentHover(ctx context.Context, params *protocol.HoverParams) (*protocol.Hover, error) {
-	file := s.parser.Files()[params.TextDocument.URI.Filename()]
+	filename := params.TextDocument.URI.Filename()
+
+	file, pos, err := s.filePos(params.TextDocument.URI, params.Position)
+	if err != nil {
+		return nil, err
+	}
 
 	body := file.Body
+	files := s.scopedFiles(filename)
 
-	byteOffset := CalculateByteOffset(params.Position, s.parser.Files()[params.TextDocument.URI.Filename()].Bytes)
+	evalCtx := s.evalContext(filename)
 
-	pos := hcl.InitialPos
-	pos.Byte = int(byteOffset)
+	x := []string{CollectVariableHover(body, pos, files)}
 
-	x := CollectHoverInfo(body, hcl.Pos{
-		Line:   int(params.Position.Line),
-		Column: int(params.Position.Character),
-		Byte:   pos.Byte,
-	})
+	if x[0] == "" {
+		x = []string{CollectLocalHover(body, pos, files, evalCtx)}
+	}
+
+	if x[0] == "" {
+		x = []string{CollectFunctionHover(body, pos)}
+	}
+
+	if x[0] == "" {
+		x = CollectHoverInfo(body, pos, evalCtx)
+	}
+
+	if len(x) == 0 || x[len(x)-1] == "" {
+		x = []string{CollectValueHover(body, pos, evalCtx)}
+	}
 
 	s.logger.Info(fmt.Sprintf("arr: %v", x))
 
-	if len(x) == 0 {
+	if x[len(x)-1] == "" {
 		return nil, nil
 	}
 
 	return &protocol.Hover{
 		Contents: protocol.MarkupContent{
-			Kind:  protocol.PlainText,
-			Value: fmt.Sprintf("%s", x[len(x)-1]),
+			Kind:  protocol.Markdown,
+			Value: x[len(x)-1],
 		},
 	}, nil
 }
@@ -78,11 +218,60 @@ func (s *Service) HandleTextDocumentCompletion(ctx context.Context, params *prot
 	pos := hcl.InitialPos
 	pos.Byte = int(byteOffset)
 
+	if s.parser.IsVarFile(params.TextDocument.URI.Filename()) {
+		items := []protocol.CompletionItem{}
+
+		prefix := identifierPrefix(file.Bytes, int(byteOffset))
+		if !inValuePosition(file.Bytes, int(byteOffset)-len(prefix)) {
+			items = CollectVarFileCompletions(body, s.parser.Jobs(params.TextDocument.URI.Filename()))
+		}
+
+		return &protocol.CompletionList{
+			IsIncomplete: false,
+			Items:        items,
+		}, nil
+	}
+
+	if items, ok := CollectVolumeCompletions(body, int(byteOffset)); ok {
+		return &protocol.CompletionList{
+			IsIncomplete: false,
+			Items:        items,
+		}, nil
+	}
+
+	if items, ok := CollectPortCompletions(body, file.Bytes, int(byteOffset)); ok {
+		return &protocol.CompletionList{
+			IsIncomplete: false,
+			Items:        items,
+		}, nil
+	}
+
+	if items, ok := CollectRuntimeCompletions(file.Bytes, int(byteOffset), s.scopedFiles(params.TextDocument.URI.Filename())); ok {
+		return &protocol.CompletionList{
+			IsIncomplete: false,
+			Items:        items,
+		}, nil
+	}
+
+	if items, ok := CollectReferenceCompletions(file.Bytes, int(byteOffset), s.scopedFiles(params.TextDocument.URI.Filename())); ok {
+		return &protocol.CompletionList{
+			IsIncomplete: false,
+			Items:        items,
+		}, nil
+	}
+
+	if items, ok := CollectFunctionCompletions(file.Bytes, int(byteOffset)); ok {
+		return &protocol.CompletionList{
+			IsIncomplete: false,
+			Items:        items,
+		}, nil
+	}
+
 	completions := CollectCompletions(body, hcl.Pos{
 		Line:   int(params.Position.Line),
 		Column: int(params.Position.Character),
 		Byte:   pos.Byte,
-	})
+	}, s.evalContext(params.TextDocument.URI.Filename()))
 
 	return &protocol.CompletionList{
 		IsIncomplete: false,
@@ -90,12 +279,367 @@ func (s *Service) HandleTextDocumentCompletion(ctx context.Context, params *prot
 	}, nil
 }
 
+// CommandSelectVarFiles selects the var-files a job is evaluated against.
+const CommandSelectVarFiles = "nomad-ls.selectVarFiles"
+
+// HandleWorkspaceExecuteCommand runs the commands advertised by the server.
+// CommandSelectVarFiles takes the URI of a job followed by the URIs of the
+// var-files to evaluate it against, restoring the linked var-files when no
+// var-file is given.
+func (s *Service) HandleWorkspaceExecuteCommand(ctx context.Context, params *protocol.ExecuteCommandParams) (any, error) {
+	switch params.Command {
+	case CommandSelectVarFiles:
+		var filenames []string
+
+		for _, arg := range params.Arguments {
+			documentURI, ok := arg.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid argument %v, expected a document uri", arg)
+			}
+
+			filenames = append(filenames, protocol.DocumentURI(documentURI).Filename())
+		}
+
+		if len(filenames) == 0 {
+			return nil, errors.New("missing job document uri")
+		}
+
+		s.parser.SelectVarFiles(filenames[0], filenames[1:])
+
+		// the job and its var-files are evaluated against the new selection
+		s.republishDiagnostics()
+
+		return nil, nil
+	}
+
+	return nil, fmt.Errorf("unknown command %q", params.Command)
+}
+
+func (s *Service) HandleTextDocumentSignatureHelp(ctx context.Context, params *protocol.SignatureHelpParams) (*protocol.SignatureHelp, error) {
+	file := s.parser.Files()[params.TextDocument.URI.Filename()]
+
+	if file == nil {
+		return nil, errors.New("file is nil")
+	}
+
+	byteOffset := CalculateByteOffset(params.Position, file.Bytes)
+
+	return CollectSignatureHelp(file.Bytes, int(byteOffset)), nil
+}
+
+func (s *Service) HandleTextDocumentDefinition(ctx context.Context, params *protocol.DefinitionParams) ([]protocol.Location, error) {
+	filename := params.TextDocument.URI.Filename()
+
+	file, pos, err := s.filePos(params.TextDocument.URI, params.Position)
+	if err != nil {
+		return nil, err
+	}
+
+	ranges := CollectDefinitions(file.Body, pos, s.scopedFiles(filename))
+
+	return asLocations(ranges), nil
+}
+
+func (s *Service) HandleTextDocumentReferences(ctx context.Context, params *protocol.ReferenceParams) ([]protocol.Location, error) {
+	filename := params.TextDocument.URI.Filename()
+
+	file, pos, err := s.filePos(params.TextDocument.URI, params.Position)
+	if err != nil {
+		return nil, err
+	}
+
+	name, _, ok := FindVariable(file.Body, pos)
+	if !ok {
+		return nil, nil
+	}
+
+	ranges := CollectReferences(name, s.scopedFiles(filename), params.Context.IncludeDeclaration)
+
+	return asLocations(ranges), nil
+}
+
+func (s *Service) HandleTextDocumentPrepareRename(ctx context.Context, params *protocol.PrepareRenameParams) (*protocol.Range, error) {
+	file, pos, err := s.filePos(params.TextDocument.URI, params.Position)
+	if err != nil {
+		return nil, err
+	}
+
+	_, r, ok := FindVariable(file.Body, pos)
+	if !ok {
+		return nil, nil
+	}
+
+	rng := protocolRange(r)
+
+	return &rng, nil
+}
+
+func (s *Service) HandleTextDocumentRename(ctx context.Context, params *protocol.RenameParams) (*protocol.WorkspaceEdit, error) {
+	filename := params.TextDocument.URI.Filename()
+
+	file, pos, err := s.filePos(params.TextDocument.URI, params.Position)
+	if err != nil {
+		return nil, err
+	}
+
+	name, _, ok := FindVariable(file.Body, pos)
+	if !ok {
+		return nil, errors.New("no variable found at position")
+	}
+
+	if !hclsyntax.ValidIdentifier(params.NewName) {
+		return nil, fmt.Errorf("%q is not a valid variable name", params.NewName)
+	}
+
+	changes := map[protocol.DocumentURI][]protocol.TextEdit{}
+
+	for editFilename, edits := range CollectRenameEdits(name, params.NewName, s.scopedFiles(filename)) {
+		changes[uri.File(editFilename)] = edits
+	}
+
+	return &protocol.WorkspaceEdit{
+		Changes: changes,
+	}, nil
+}
+
+// inWorkspace reports whether filename lives under one of the workspace folders.
+func (s *Service) inWorkspace(filename string) bool {
+	for _, folder := range s.workspaceFolders {
+		rel, err := filepath.Rel(folder, filename)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// scopedFiles returns the siblings of filename whose declarations are in scope
+// in filename, including filename itself.
+func (s *Service) scopedFiles(filename string) map[string]*hcl.File {
+	return scopedFiles(filename, s.parser.Siblings(filename))
+}
+
+// evalContext returns the context used to evaluate the expressions of the job
+// in filename, built from the sibling files in its scope, the var-files
+// configured for the workspace and the var-files selected for or linked to
+// the job.
+func (s *Service) evalContext(filename string) *hcl.EvalContext {
+	var varFiles []*hcl.File
+
+	for _, name := range s.varFiles {
+		if file := s.parser.File(name); file != nil {
+			varFiles = append(varFiles, file)
+		}
+	}
+
+	varFiles = append(varFiles, s.parser.VarFiles(filename)...)
+
+	return NewEvalContext(s.scopedFiles(filename), varFiles)
+}
+
+// initializationOptions are the settings clients can pass when initializing
+// the server, e.g. `{"varFiles": ["prod.vars.hcl"]}`.
+type initializationOptions struct {
+	// VarFiles are var-files whose values override the defaults of
+	// variables in every job. Relative paths are resolved against the first
+	// workspace folder.
+	VarFiles []string `json:"varFiles"`
+
+	// VarFilePatterns are glob patterns recognising var-files by their base
+	// name, defaulting to parser.DefaultVarFilePatterns.
+	VarFilePatterns []string `json:"varFilePatterns"`
+}
+
+// configure applies the initialization options of params.
+func (s *Service) configure(params *protocol.InitializeParams) {
+	var options initializationOptions
+
+	raw, err := json.Marshal(params.InitializationOptions)
+	if err != nil || json.Unmarshal(raw, &options) != nil {
+		return
+	}
+
+	s.varFiles = nil
+
+	for _, name := range options.VarFiles {
+		if !filepath.IsAbs(name) && len(s.workspaceFolders) > 0 {
+			name = filepath.Join(s.workspaceFolders[0], name)
+		}
+
+		s.varFiles = append(s.varFiles, name)
+	}
+
+	if options.VarFilePatterns != nil {
+		s.parser.SetVarFilePatterns(options.VarFilePatterns)
+	}
+}
+
+// loadConfig loads the project configuration from the first workspace folder,
+// returning the file it was read from and its diagnostics. Invalid
+// configurations are ignored.
+func (s *Service) loadConfig() (string, hcl.Diagnostics) {
+	if len(s.workspaceFolders) == 0 {
+		return "", nil
+	}
+
+	config, filename, diags := rules.LoadConfig(s.workspaceFolders[0])
+	if diags.HasErrors() {
+		s.logger.Error(fmt.Sprintf("invalid configuration: %s", diags.Error()))
+	}
+
+	s.config.Store(config)
+
+	return filename, diags
+}
+
+// isConfigFile reports whether filename is a project configuration file at
+// the root of the first workspace folder.
+func (s *Service) isConfigFile(filename string) bool {
+	if len(s.workspaceFolders) == 0 {
+		return false
+	}
+
+	for _, name := range rules.ConfigFileNames {
+		if filename == filepath.Join(s.workspaceFolders[0], name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// workspaceFolders returns the directories of the workspace, falling back to
+// the deprecated root uri and root path for older clients.
+func workspaceFolders(params *protocol.InitializeParams) []string {
+	var folders []string
+
+	for _, folder := range params.WorkspaceFolders {
+		folders = append(folders, uri.URI(folder.URI).Filename())
+	}
+
+	if len(folders) == 0 && params.RootURI != "" {
+		folders = append(folders, params.RootURI.Filename())
+	}
+
+	if len(folders) == 0 && params.RootPath != "" {
+		folders = append(folders, params.RootPath)
+	}
+
+	return folders
+}
+
+// filePos returns the open file for documentURI along with the hcl position
+// matching position.
+func (s *Service) filePos(documentURI protocol.DocumentURI, position protocol.Position) (*hcl.File, hcl.Pos, error) {
+	file := s.parser.Files()[documentURI.Filename()]
+
+	if file == nil {
+		return nil, hcl.Pos{}, errors.New("file is nil")
+	}
+
+	byteOffset := CalculateByteOffset(position, file.Bytes)
+
+	return file, hcl.Pos{
+		Line:   int(position.Line),
+		Column: int(position.Character),
+		Byte:   int(byteOffset),
+	}, nil
+}
+
+func asLocations(ranges []hcl.Range) []protocol.Location {
+	locations := []protocol.Location{}
+
+	for _, r := range ranges {
+		locations = append(locations, protocol.Location{
+			URI:   uri.File(r.Filename),
+			Range: protocolRange(r),
+		})
+	}
+
+	return locations
+}
+
+func (s *Service) HandleTextDocumentDocumentSymbol(ctx context.Context, params *protocol.DocumentSymbolParams) ([]protocol.DocumentSymbol, error) {
+	filename := params.TextDocument.URI.Filename()
+
+	file := s.parser.Files()[filename]
+
+	if file == nil {
+		return nil, errors.New("file is nil")
+	}
+
+	return CollectDocumentSymbols(file.Body, s.evalContext(filename)), nil
+}
+
+func (s *Service) HandleWorkspaceSymbol(ctx context.Context, params *protocol.WorkspaceSymbolParams) ([]protocol.SymbolInformation, error) {
+	return CollectWorkspaceSymbols(s.parser.WorkspaceFiles(), params.Query), nil
+}
+
+func (s *Service) HandleSemanticTokensFull(ctx context.Context, params *protocol.SemanticTokensParams) (*protocol.SemanticTokens, error) {
+	filename := params.TextDocument.URI.Filename()
+
+	file := s.parser.Files()[filename]
+
+	if file == nil {
+		return nil, errors.New("file is nil")
+	}
+
+	return &protocol.SemanticTokens{
+		Data: EncodeSemanticTokens(CollectSemanticTokens(file.Body, s.evalContext(filename)), nil),
+	}, nil
+}
+
+func (s *Service) HandleSemanticTokensRange(ctx context.Context, params *protocol.SemanticTokensRangeParams) (*protocol.SemanticTokens, error) {
+	filename := params.TextDocument.URI.Filename()
+
+	file := s.parser.Files()[filename]
+
+	if file == nil {
+		return nil, errors.New("file is nil")
+	}
+
+	return &protocol.SemanticTokens{
+		Data: EncodeSemanticTokens(CollectSemanticTokens(file.Body, s.evalContext(filename)), &params.Range),
+	}, nil
+}
+
+func (s *Service) HandleTextDocumentFoldingRange(ctx context.Context, params *protocol.FoldingRangeParams) ([]protocol.FoldingRange, error) {
+	file := s.parser.Files()[params.TextDocument.URI.Filename()]
+
+	if file == nil {
+		return nil, errors.New("file is nil")
+	}
+
+	return CollectFoldingRanges(file.Body), nil
+}
+
+func (s *Service) HandleTextDocumentCodeAction(ctx context.Context, params *protocol.CodeActionParams) ([]protocol.CodeAction, error) {
+	file := s.parser.Files()[params.TextDocument.URI.Filename()]
+
+	if file == nil {
+		return nil, errors.New("file is nil")
+	}
+
+	return CollectCodeActions(file, params.TextDocument.URI, params.Context.Diagnostics, s.evalContext(params.TextDocument.URI.Filename())), nil
+}
+
+func (s *Service) HandleTextDocumentInlayHint(ctx context.Context, params *InlayHintParams) ([]InlayHint, error) {
+	filename := params.TextDocument.URI.Filename()
+
+	file := s.parser.Files()[filename]
+
+	if file == nil {
+		return nil, errors.New("file is nil")
+	}
+
+	return CollectInlayHints(file.Body, params.Range, s.evalContext(filename)), nil
+}
+
 func (s *Service) HandleTextDocumentDidOpen(ctx context.Context, params *protocol.DidOpenTextDocumentParams) (*hcl.Diagnostics, error) {
 	file, diags := s.parser.ParseHCL([]byte(params.TextDocument.Text), params.TextDocument.URI.Filename())
 
-	schemaDiags := CollectDiagnostics(file.Body)
-
-	allDiags := diags.Extend(*schemaDiags)
+	allDiags := diags.Extend(s.collectDiagnostics(params.TextDocument.URI.Filename(), file))
 
 	s.logger.Info(fmt.Sprintf("%+v", params))
 
@@ -112,11 +656,7 @@ func (s *Service) HandleTextDocumentDidChange(ctx context.Context, params *proto
 
 		file := s.parser.Files()[params.TextDocument.URI.Filename()]
 
-		body := file.Body
-
-		schemaDiags := CollectDiagnostics(body)
-
-		allDiags := diags.Extend(*schemaDiags)
+		allDiags := diags.Extend(s.collectDiagnostics(params.TextDocument.URI.Filename(), file))
 
 		s.logger.Info(fmt.Sprintf("diags: %+v", allDiags))
 
@@ -126,8 +666,37 @@ func (s *Service) HandleTextDocumentDidChange(ctx context.Context, params *proto
 	return nil, nil
 }
 
+// collectDiagnostics runs the schema validation of file followed by the checks
+// that need the sibling files of the job, such as references to variables and
+// local values, and the cross-field rules.
+func (s *Service) collectDiagnostics(filename string, file *hcl.File) hcl.Diagnostics {
+	if s.parser.IsVarFile(filename) {
+		return CollectVarFileDiagnostics(file.Body, s.parser.Jobs(filename))
+	}
+
+	files := s.scopedFiles(filename)
+
+	evalCtx := s.evalContext(filename)
+
+	diags := *CollectDiagnostics(file.Body, evalCtx)
+
+	diags = diags.Extend(CollectVariableReferenceDiagnostics(file.Body, files))
+	diags = diags.Extend(CollectLocalDiagnostics(file.Body, files))
+	diags = diags.Extend(rules.Run(file.Body, evalCtx, s.config.Load().Rules()))
+
+	return diags
+}
+
 func (s *Service) HandleTextDocumentDidClose(ctx context.Context, params *protocol.DidCloseTextDocumentParams) error {
-	s.parser.RemoveHCL(params.TextDocument.URI.Filename())
+	filename := params.TextDocument.URI.Filename()
+
+	s.parser.RemoveHCL(filename)
+
+	// the editor may have discarded unsaved changes, so the index has to be
+	// refreshed from disk
+	if s.inWorkspace(filename) && parser.IsNomadFile(filename) {
+		s.parser.IndexFile(filename)
+	}
 
 	s.logger.Info(fmt.Sprintf("%+v", s.parser.Files()))
 
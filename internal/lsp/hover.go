@@ -1,20 +1,26 @@
 package lsp
 
 import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
 	hclschema "github.com/hashicorp/hcl-lang/schema"
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/loczek/nomad-ls/internal/schema"
+	"github.com/zclconf/go-cty/cty"
 )
 
-func CollectHoverInfo(body hcl.Body, pos hcl.Pos) []string {
-	return []string{CollectHoverInfoDFS(body, pos, &schema.RootBodySchema)}
+func CollectHoverInfo(body hcl.Body, pos hcl.Pos, ctx *hcl.EvalContext) []string {
+	return []string{CollectHoverInfoDFS(body, pos, &schema.RootBodySchema, ctx)}
 }
 
 func CollectHoverInfoDFS(
 	body hcl.Body,
 	pos hcl.Pos,
 	langSchema *hclschema.BodySchema,
+	ctx *hcl.EvalContext,
 ) string {
 	if langSchema == nil {
 		return ""
@@ -37,13 +43,9 @@ func CollectHoverInfoDFS(
 			}
 
 			if langSchema.Blocks[k] != nil && langSchema.Blocks[k].Body != nil {
-				ans = CollectHoverInfoDFS(b.Body, pos, langSchema.Blocks[k].Body)
+				ans = CollectHoverInfoDFS(b.Body, pos, langSchema.Blocks[k].Body, ctx)
 			} else if langSchema.Blocks[k] != nil && langSchema.Blocks[k].DependentBody != nil {
-				if bodyContent.Attributes["driver"] != nil {
-					driver, _ := bodyContent.Attributes["driver"].Expr.Value(&hcl.EvalContext{})
-
-					ans = CollectHoverInfoDFS(b.Body, pos, langSchema.Blocks[k].DependentBody[hclschema.SchemaKey(driver.AsString())])
-				}
+				ans = CollectHoverInfoDFS(b.Body, pos, dependentBodySchema(langSchema.Blocks[k], bodyContent, ctx), ctx)
 			}
 		}
 	}
@@ -56,3 +58,149 @@ func CollectHoverInfoDFS(
 
 	return ans
 }
+
+// CollectVariableHover describes the variable referenced under pos, using the
+// declarations found in files. It returns an empty string when pos is not on
+// a `var.*` reference.
+func CollectVariableHover(body hcl.Body, pos hcl.Pos, files map[string]*hcl.File) string {
+	traversal := FindTraversal(body, pos)
+	if traversal == nil || traversal.RootName() != "var" {
+		return ""
+	}
+
+	name, _, ok := traversalName(traversal)
+	if !ok {
+		return ""
+	}
+
+	for _, filename := range sortedFilenames(files) {
+		src := files[filename].Bytes
+
+		for _, v := range CollectVariables(files[filename].Body) {
+			if v.Name != name {
+				continue
+			}
+
+			typ := "any"
+			if v.Type != nil {
+				typ = string(v.Type.Range().SliceBytes(src))
+			}
+
+			var sb strings.Builder
+
+			fmt.Fprintf(&sb, "**var.%s** `%s`\n\n", name, typ)
+
+			if v.Description != nil {
+				if val, diags := v.Description.Value(&hcl.EvalContext{}); !diags.HasErrors() && val.Type() == cty.String && val.IsKnown() && !val.IsNull() {
+					fmt.Fprintf(&sb, "%s\n\n", val.AsString())
+				}
+			}
+
+			if v.Default != nil {
+				fmt.Fprintf(&sb, "Default: `%s`\n\n", v.Default.Range().SliceBytes(src))
+			} else {
+				sb.WriteString("Required, no default value\n\n")
+			}
+
+			fmt.Fprintf(&sb, "Declared in `%s:%d`", filepath.Base(filename), v.DefRange.Start.Line)
+
+			return sb.String()
+		}
+	}
+
+	return fmt.Sprintf("**var.%s**\n\nUndeclared variable", name)
+}
+
+// CollectLocalHover describes the local value referenced under pos, along
+// with its value evaluated in ctx when it is statically known. It returns an
+// empty string when pos is not on a `local.*` reference.
+func CollectLocalHover(body hcl.Body, pos hcl.Pos, files map[string]*hcl.File, ctx *hcl.EvalContext) string {
+	traversal := FindTraversal(body, pos)
+	if traversal == nil || traversal.RootName() != "local" {
+		return ""
+	}
+
+	name, _, ok := traversalName(traversal)
+	if !ok {
+		return ""
+	}
+
+	for _, filename := range sortedFilenames(files) {
+		for _, l := range CollectLocals(files[filename].Body) {
+			if l.Name != name {
+				continue
+			}
+
+			var sb strings.Builder
+
+			fmt.Fprintf(&sb, "**local.%s**\n\n", name)
+
+			if val, diags := l.Expr.Value(ctx); !diags.HasErrors() && val.IsWhollyKnown() {
+				fmt.Fprintf(&sb, "```hcl\n%s\n```\n\n", formatValue(val))
+			} else {
+				fmt.Fprintf(&sb, "`%s`\n\n", l.Expr.Range().SliceBytes(files[filename].Bytes))
+			}
+
+			fmt.Fprintf(&sb, "Declared in `%s:%d`", filepath.Base(filename), l.DefRange.Start.Line)
+
+			return sb.String()
+		}
+	}
+
+	return fmt.Sprintf("**local.%s**\n\nUndeclared local value", name)
+}
+
+// CollectValueHover returns the statically evaluated value of the innermost
+// non-literal expression under pos, or an empty string when there is none or
+// its value is not known.
+func CollectValueHover(body hcl.Body, pos hcl.Pos, ctx *hcl.EvalContext) string {
+	syntaxBody, ok := body.(*hclsyntax.Body)
+	if !ok {
+		return ""
+	}
+
+	var innermost hclsyntax.Expression
+
+	hclsyntax.VisitAll(syntaxBody, func(node hclsyntax.Node) hcl.Diagnostics {
+		expr, ok := node.(hclsyntax.Expression)
+		if !ok || !expr.Range().ContainsPos(pos) || isLiteral(expr) {
+			return nil
+		}
+
+		if innermost == nil || rangeLength(expr.Range()) <= rangeLength(innermost.Range()) {
+			innermost = expr
+		}
+
+		return nil
+	})
+
+	if innermost == nil {
+		return ""
+	}
+
+	val, diags := innermost.Value(ctx)
+	if diags.HasErrors() || !val.IsWhollyKnown() {
+		return ""
+	}
+
+	return fmt.Sprintf("```hcl\n%s\n```", formatValue(val))
+}
+
+// isLiteral reports whether expr is a literal whose value is obvious from its
+// source, such as a number or a string without interpolations.
+func isLiteral(expr hclsyntax.Expression) bool {
+	switch e := expr.(type) {
+	case *hclsyntax.LiteralValueExpr, *hclsyntax.ObjectConsKeyExpr:
+		return true
+	case *hclsyntax.TemplateExpr:
+		return e.IsStringLiteral()
+	case *hclsyntax.TupleConsExpr, *hclsyntax.ObjectConsExpr:
+		return len(expr.Variables()) == 0
+	}
+
+	return false
+}
+
+func rangeLength(r hcl.Range) int {
+	return r.End.Byte - r.Start.Byte
+}
@@ -0,0 +1,187 @@
+package lsp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	hclschema "github.com/hashicorp/hcl-lang/schema"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/loczek/nomad-ls/internal/schema"
+	"github.com/zclconf/go-cty/cty"
+	"go.lsp.dev/protocol"
+)
+
+// Hints are kept short so that they do not push the code off screen, the
+// complete list of defaults is available in the tooltip.
+const (
+	maxInlayHintValueLength = 40
+	maxInlayHintDefaults    = 6
+)
+
+// CollectInlayHints returns the hints within r. Every block gets a hint after
+// its closing brace listing the defaults of the attributes it does not set,
+// and every `var.*` reference gets a hint with the value of the variable in
+// ctx, which is its default unless set by a var-file.
+func CollectInlayHints(body hcl.Body, r protocol.Range, ctx *hcl.EvalContext) []InlayHint {
+	hints := []InlayHint{}
+
+	CollectInlayHintsDFS(body, &hints, &schema.RootBodySchema, ctx)
+	collectVariableInlayHints(body, &hints, ctx)
+
+	inRange := []InlayHint{}
+	for _, hint := range hints {
+		if hint.Position.Line >= r.Start.Line && hint.Position.Line <= r.End.Line {
+			inRange = append(inRange, hint)
+		}
+	}
+
+	sort.SliceStable(inRange, func(i, j int) bool {
+		if inRange[i].Position.Line != inRange[j].Position.Line {
+			return inRange[i].Position.Line < inRange[j].Position.Line
+		}
+		return inRange[i].Position.Character < inRange[j].Position.Character
+	})
+
+	return inRange
+}
+
+func CollectInlayHintsDFS(body hcl.Body, hints *[]InlayHint, langSchema *hclschema.BodySchema, ctx *hcl.EvalContext) {
+	if langSchema == nil {
+		return
+	}
+
+	bodyContent, _, _ := body.PartialContent(langSchema.ToHCLSchema())
+
+	for _, b := range bodyContent.Blocks {
+		var childSchema *hclschema.BodySchema
+
+		if langSchema.Blocks[b.Type] != nil && langSchema.Blocks[b.Type].Body != nil {
+			childSchema = langSchema.Blocks[b.Type].Body
+		} else if langSchema.Blocks[b.Type] != nil && langSchema.Blocks[b.Type].DependentBody != nil {
+			childSchema = dependentBodySchema(langSchema.Blocks[b.Type], bodyContent, ctx)
+		}
+
+		if childSchema == nil {
+			continue
+		}
+
+		// hints are placed after the closing brace of native syntax bodies
+		syntaxBody, ok := b.Body.(*hclsyntax.Body)
+		if !ok {
+			continue
+		}
+
+		if defaults := omittedDefaults(b.Body, childSchema); len(defaults) > 0 {
+			label := strings.Join(defaults, ", ")
+			if len(defaults) > maxInlayHintDefaults {
+				label = fmt.Sprintf("%s, +%d more", strings.Join(defaults[:maxInlayHintDefaults], ", "), len(defaults)-maxInlayHintDefaults)
+			}
+
+			*hints = append(*hints, InlayHint{
+				Position:    protocolRange(syntaxBody.SrcRange).End,
+				Label:       label,
+				Tooltip:     fmt.Sprintf("Defaults of the %s block:\n%s", b.Type, strings.Join(defaults, "\n")),
+				PaddingLeft: true,
+			})
+		}
+
+		CollectInlayHintsDFS(b.Body, hints, childSchema, ctx)
+	}
+}
+
+// omittedDefaults returns the `name = value` pairs of the attributes of
+// langSchema that are not set in body and have a meaningful default.
+func omittedDefaults(body hcl.Body, langSchema *hclschema.BodySchema) []string {
+	var defaults []string
+
+	bodyContent, _, _ := body.PartialContent(langSchema.ToHCLSchema())
+
+	names := make([]string, 0, len(langSchema.Attributes))
+	for k := range langSchema.Attributes {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	for _, k := range names {
+		v := langSchema.Attributes[k]
+
+		if bodyContent.Attributes[k] != nil || v.IsDeprecated {
+			continue
+		}
+
+		d, ok := defaultValue(v)
+		if !ok || !isMeaningfulValue(d) {
+			continue
+		}
+
+		defaults = append(defaults, fmt.Sprintf("%s = %s", k, truncate(formatValue(d))))
+	}
+
+	return defaults
+}
+
+func collectVariableInlayHints(body hcl.Body, hints *[]InlayHint, ctx *hcl.EvalContext) {
+	if ctx == nil {
+		return
+	}
+
+	vars, ok := ctx.Variables["var"]
+	if !ok || !vars.Type().IsObjectType() {
+		return
+	}
+
+	for _, traversal := range CollectTraversals(body, "var") {
+		name, r, ok := traversalName(traversal)
+		if !ok || !vars.Type().HasAttribute(name) {
+			continue
+		}
+
+		val := vars.GetAttr(name)
+		if !val.IsWhollyKnown() {
+			continue
+		}
+
+		*hints = append(*hints, InlayHint{
+			Position:    protocolRange(r).End,
+			Label:       "= " + truncate(formatValue(val)),
+			Kind:        InlayHintKindParameter,
+			Tooltip:     fmt.Sprintf("Value of var.%s", name),
+			PaddingLeft: true,
+		})
+	}
+}
+
+// isMeaningfulValue reports whether a default is worth showing, which
+// excludes null values and empty strings or collections.
+func isMeaningfulValue(val cty.Value) bool {
+	if val == cty.NilVal || val.IsNull() || !val.IsWhollyKnown() {
+		return false
+	}
+
+	if val.Type() == cty.String {
+		return val.AsString() != ""
+	}
+
+	if val.CanIterateElements() {
+		return val.LengthInt() > 0
+	}
+
+	return true
+}
+
+// truncate puts text on a single line and shortens it to at most
+// maxInlayHintValueLength characters.
+func truncate(text string) string {
+	if strings.Contains(text, "\n") {
+		text = strings.Join(strings.Fields(text), " ")
+	}
+
+	runes := []rune(text)
+	if len(runes) <= maxInlayHintValueLength {
+		return text
+	}
+
+	return string(runes[:maxInlayHintValueLength]) + "…"
+}
@@ -0,0 +1,154 @@
+package lsp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/loczek/nomad-ls/internal/rules"
+	"github.com/loczek/nomad-ls/internal/schema"
+)
+
+// Local is a local value declared as an attribute of a `locals` block.
+type Local struct {
+	Name      string
+	NameRange hcl.Range
+	DefRange  hcl.Range
+	Expr      hcl.Expression
+}
+
+// CollectLocals returns the local values declared at the root of body,
+// ordered by their position in the file.
+func CollectLocals(body hcl.Body) []Local {
+	var locals []Local
+
+	bodyContent, _, _ := body.PartialContent(schema.RootBodySchema.ToHCLSchema())
+
+	for _, b := range bodyContent.Blocks.OfType("locals") {
+		attrs, _ := b.Body.JustAttributes()
+
+		for name, attr := range attrs {
+			locals = append(locals, Local{
+				Name:      name,
+				NameRange: attr.NameRange,
+				DefRange:  attr.Range,
+				Expr:      attr.Expr,
+			})
+		}
+	}
+
+	sort.Slice(locals, func(i, j int) bool {
+		return locals[i].DefRange.Start.Byte < locals[j].DefRange.Start.Byte
+	})
+
+	return locals
+}
+
+// collectLocalsByName returns the local values declared in files by name.
+// When a local value is declared more than once the first declaration wins.
+func collectLocalsByName(files map[string]*hcl.File) map[string]Local {
+	locals := map[string]Local{}
+
+	for _, filename := range sortedFilenames(files) {
+		for _, l := range CollectLocals(files[filename].Body) {
+			if _, ok := locals[l.Name]; !ok {
+				locals[l.Name] = l
+			}
+		}
+	}
+
+	return locals
+}
+
+// localDependencies returns the names of the local values referenced by expr.
+func localDependencies(expr hcl.Expression) []string {
+	var names []string
+
+	for _, traversal := range expr.Variables() {
+		if traversal.RootName() != "local" {
+			continue
+		}
+
+		if name, _, ok := traversalName(traversal); ok {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// CollectLocalDiagnostics reports the `local.*` references in body that do
+// not refer to a local value declared in files, and the local values of body
+// that depend on themselves.
+func CollectLocalDiagnostics(body hcl.Body, files map[string]*hcl.File) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+
+	locals := collectLocalsByName(files)
+
+	for _, traversal := range CollectTraversals(body, "local") {
+		name, r, ok := traversalName(traversal)
+		if !ok {
+			continue
+		}
+
+		if _, ok := locals[name]; !ok {
+			diags = diags.Append(&hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Reference to undeclared local value",
+				Detail:   fmt.Sprintf("A local value with the name %q has not been declared.", name),
+				Subject:  r.Ptr(),
+				Extra:    rules.DiagnosticExtra("undeclared-local"),
+			})
+		}
+	}
+
+	for _, l := range CollectLocals(body) {
+		if cycle := localCycle(l.Name, locals); cycle != nil {
+			diags = diags.Append(&hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Cycle in local values",
+				Detail:   fmt.Sprintf("The local value %q depends on itself: %s.", l.Name, strings.Join(cycle, " -> ")),
+				Subject:  l.NameRange.Ptr(),
+				Extra:    rules.DiagnosticExtra("local-cycle"),
+			})
+		}
+	}
+
+	return diags
+}
+
+// localCycle returns the path of references leading from the local value name
+// back to itself, e.g. `local.a -> local.b -> local.a`, or nil when there is
+// no such path.
+func localCycle(name string, locals map[string]Local) []string {
+	visited := map[string]bool{}
+
+	var visit func(current string, path []string) []string
+	visit = func(current string, path []string) []string {
+		l, ok := locals[current]
+		if !ok {
+			return nil
+		}
+
+		for _, dep := range localDependencies(l.Expr) {
+			if dep == name {
+				return append(path, "local."+dep)
+			}
+
+			if visited[dep] {
+				continue
+			}
+			visited[dep] = true
+
+			next := append(append([]string{}, path...), "local."+dep)
+			if cycle := visit(dep, next); cycle != nil {
+				return cycle
+			}
+		}
+
+		return nil
+	}
+
+	return visit(name, []string{"local." + name})
+}
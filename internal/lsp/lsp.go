@@ -7,11 +7,15 @@ import (
 	"log"
 	"log/slog"
 	"strings"
+	"sync/atomic"
 	"unicode/utf8"
 
+	hclschema "github.com/hashicorp/hcl-lang/schema"
 	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
 
 	"github.com/loczek/nomad-ls/internal/parser"
+	"github.com/loczek/nomad-ls/internal/rules"
 
 	"go.lsp.dev/jsonrpc2"
 	"go.lsp.dev/protocol"
@@ -22,6 +26,16 @@ type Service struct {
 	parser    parser.Parser
 	schemaMap map[string]*hcl.BodySchema
 	logger    slog.Logger
+
+	workspaceFolders []string
+	varFiles         []string
+
+	// config is the project configuration of the rules, which is nil when
+	// the workspace has none.
+	config atomic.Pointer[rules.Config]
+
+	// watchFiles is set when the client can register file watchers.
+	watchFiles bool
 }
 
 func New(con jsonrpc2.Conn, logger slog.Logger) Service {
@@ -35,13 +49,23 @@ func New(con jsonrpc2.Conn, logger slog.Logger) Service {
 func (s *Service) Handle(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) (any, error) {
 	switch req.Method() {
 	case protocol.MethodInitialize:
-		params := protocol.InitializedParams{}
+		params := protocol.InitializeParams{}
 		err := json.Unmarshal(req.Params(), &params)
 		if err != nil {
 			return nil, err
 		}
 
 		return s.HandleInitialize(ctx, &params)
+	case protocol.MethodInitialized:
+		return nil, s.HandleInitialized(ctx)
+	case protocol.MethodWorkspaceDidChangeWatchedFiles:
+		params := protocol.DidChangeWatchedFilesParams{}
+		err := json.Unmarshal(req.Params(), &params)
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, s.HandleWorkspaceDidChangeWatchedFiles(ctx, &params)
 	case protocol.MethodTextDocumentHover:
 		params := protocol.HoverParams{}
 		err := json.Unmarshal(req.Params(), &params)
@@ -62,6 +86,110 @@ func (s *Service) Handle(ctx context.Context, reply jsonrpc2.Replier, req jsonrp
 		s.logger.Info(fmt.Sprintf("%+v", params))
 
 		return s.HandleTextDocumentCompletion(ctx, &params)
+	case protocol.MethodWorkspaceExecuteCommand:
+		params := protocol.ExecuteCommandParams{}
+		err := json.Unmarshal(req.Params(), &params)
+		if err != nil {
+			return nil, err
+		}
+
+		return s.HandleWorkspaceExecuteCommand(ctx, &params)
+	case protocol.MethodTextDocumentSignatureHelp:
+		params := protocol.SignatureHelpParams{}
+		err := json.Unmarshal(req.Params(), &params)
+		if err != nil {
+			return nil, err
+		}
+
+		return s.HandleTextDocumentSignatureHelp(ctx, &params)
+	case protocol.MethodTextDocumentDefinition:
+		params := protocol.DefinitionParams{}
+		err := json.Unmarshal(req.Params(), &params)
+		if err != nil {
+			return nil, err
+		}
+
+		return s.HandleTextDocumentDefinition(ctx, &params)
+	case protocol.MethodTextDocumentReferences:
+		params := protocol.ReferenceParams{}
+		err := json.Unmarshal(req.Params(), &params)
+		if err != nil {
+			return nil, err
+		}
+
+		return s.HandleTextDocumentReferences(ctx, &params)
+	case protocol.MethodTextDocumentPrepareRename:
+		params := protocol.PrepareRenameParams{}
+		err := json.Unmarshal(req.Params(), &params)
+		if err != nil {
+			return nil, err
+		}
+
+		return s.HandleTextDocumentPrepareRename(ctx, &params)
+	case protocol.MethodTextDocumentRename:
+		params := protocol.RenameParams{}
+		err := json.Unmarshal(req.Params(), &params)
+		if err != nil {
+			return nil, err
+		}
+
+		return s.HandleTextDocumentRename(ctx, &params)
+	case protocol.MethodTextDocumentDocumentSymbol:
+		params := protocol.DocumentSymbolParams{}
+		err := json.Unmarshal(req.Params(), &params)
+		if err != nil {
+			return nil, err
+		}
+
+		return s.HandleTextDocumentDocumentSymbol(ctx, &params)
+	case protocol.MethodWorkspaceSymbol:
+		params := protocol.WorkspaceSymbolParams{}
+		err := json.Unmarshal(req.Params(), &params)
+		if err != nil {
+			return nil, err
+		}
+
+		return s.HandleWorkspaceSymbol(ctx, &params)
+	case protocol.MethodSemanticTokensFull:
+		params := protocol.SemanticTokensParams{}
+		err := json.Unmarshal(req.Params(), &params)
+		if err != nil {
+			return nil, err
+		}
+
+		return s.HandleSemanticTokensFull(ctx, &params)
+	case protocol.MethodSemanticTokensRange:
+		params := protocol.SemanticTokensRangeParams{}
+		err := json.Unmarshal(req.Params(), &params)
+		if err != nil {
+			return nil, err
+		}
+
+		return s.HandleSemanticTokensRange(ctx, &params)
+	case protocol.MethodTextDocumentFoldingRange:
+		params := protocol.FoldingRangeParams{}
+		err := json.Unmarshal(req.Params(), &params)
+		if err != nil {
+			return nil, err
+		}
+
+		return s.HandleTextDocumentFoldingRange(ctx, &params)
+	case protocol.MethodTextDocumentCodeAction:
+		params := protocol.CodeActionParams{}
+		err := json.Unmarshal(req.Params(), &params)
+		if err != nil {
+			return nil, err
+		}
+
+		return s.HandleTextDocumentCodeAction(ctx, &params)
+	case MethodTextDocumentInlayHint:
+		params := InlayHintParams{}
+		err := json.Unmarshal(req.Params(), &params)
+		if err != nil {
+			return nil, err
+		}
+
+		return s.HandleTextDocumentInlayHint(ctx, &params)
 	case protocol.MethodTextDocumentDidOpen:
 		params := protocol.DidOpenTextDocumentParams{}
 		err := json.Unmarshal(req.Params(), &params)
@@ -71,32 +199,7 @@ func (s *Service) Handle(ctx context.Context, reply jsonrpc2.Replier, req jsonrp
 		diag, err := s.HandleTextDocumentDidOpen(ctx, &params)
 
 		if diag != nil {
-			protocolDiagnostics := []protocol.Diagnostic{}
-
-			for _, v := range *diag {
-				protocolDiagnostics = append(protocolDiagnostics, protocol.Diagnostic{
-					Source:   "nomad-ls",
-					Severity: protocol.DiagnosticSeverity(v.Severity),
-					Range: protocol.Range{
-						Start: protocol.Position{
-							Line:      uint32(v.Subject.Start.Line - 1),
-							Character: uint32(v.Subject.Start.Column - 1),
-						},
-						End: protocol.Position{
-							Line:      uint32(v.Subject.End.Line - 1),
-							Character: uint32(v.Subject.End.Column - 1),
-						},
-					},
-					Message: v.Detail,
-				})
-			}
-
-			log.Printf("diagnostics: %+v", protocolDiagnostics)
-			s.con.Notify(context.Background(), "textDocument/publishDiagnostics", protocol.PublishDiagnosticsParams{
-				URI:         params.TextDocument.URI,
-				Version:     uint32(params.TextDocument.Version),
-				Diagnostics: protocolDiagnostics,
-			})
+			s.publishDiagnostics(params.TextDocument.URI, uint32(params.TextDocument.Version), *diag)
 		}
 
 		return nil, err
@@ -110,31 +213,7 @@ func (s *Service) Handle(ctx context.Context, reply jsonrpc2.Replier, req jsonrp
 		diag, err := s.HandleTextDocumentDidChange(ctx, &params)
 
 		if diag != nil {
-			protocolDiagnostics := []protocol.Diagnostic{}
-
-			for _, v := range *diag {
-				protocolDiagnostics = append(protocolDiagnostics, protocol.Diagnostic{
-					Source: "nomad-ls",
-					Range: protocol.Range{
-						Start: protocol.Position{
-							Line:      uint32(v.Subject.Start.Line - 1),
-							Character: uint32(v.Subject.Start.Column - 1),
-						},
-						End: protocol.Position{
-							Line:      uint32(v.Subject.End.Line - 1),
-							Character: uint32(v.Subject.End.Column - 1),
-						},
-					},
-					Message: v.Detail,
-				})
-			}
-
-			log.Printf("diagnostics: %+v", protocolDiagnostics)
-			s.con.Notify(context.Background(), "textDocument/publishDiagnostics", protocol.PublishDiagnosticsParams{
-				URI:         params.TextDocument.URI,
-				Version:     uint32(params.TextDocument.Version),
-				Diagnostics: protocolDiagnostics,
-			})
+			s.publishDiagnostics(params.TextDocument.URI, uint32(params.TextDocument.Version), *diag)
 		}
 
 		return nil, err
@@ -163,6 +242,85 @@ func (s *Service) Handle(ctx context.Context, reply jsonrpc2.Replier, req jsonrp
 	return nil, nil
 }
 
+// publishDiagnostics publishes diags as the diagnostics of the document at
+// documentURI, replacing the ones published before. Diagnostics suppressed by
+// the comments of an open document are left out.
+func (s *Service) publishDiagnostics(documentURI protocol.DocumentURI, version uint32, diags hcl.Diagnostics) {
+	if file := s.parser.Files()[documentURI.Filename()]; file != nil {
+		config := s.config.Load()
+		diags = config.Apply(FilterSuppressedDiagnostics(file, diags, config))
+	}
+
+	protocolDiagnostics := asProtocolDiagnostics(diags)
+
+	log.Printf("diagnostics: %+v", protocolDiagnostics)
+	s.con.Notify(context.Background(), "textDocument/publishDiagnostics", protocol.PublishDiagnosticsParams{
+		URI:         documentURI,
+		Version:     version,
+		Diagnostics: protocolDiagnostics,
+	})
+}
+
+// DiagHint is the severity of diagnostics published as hints, for which hcl
+// has no severity of its own. asProtocolDiagnostics converts severities by
+// value, so it is the protocol hint severity.
+const DiagHint = hcl.DiagnosticSeverity(protocol.DiagnosticSeverityHint)
+
+// asProtocolDiagnostics converts diagnostics to the protocol representation
+// published to the client, with the ID of the rule reporting them as their
+// code. Diagnostics without a subject cannot be placed in the document and are
+// skipped.
+func asProtocolDiagnostics(diags hcl.Diagnostics) []protocol.Diagnostic {
+	protocolDiagnostics := []protocol.Diagnostic{}
+
+	for _, v := range diags {
+		if v.Subject == nil {
+			continue
+		}
+
+		var code any
+		if id := rules.ID(v); id != "" {
+			code = id
+		}
+
+		protocolDiagnostics = append(protocolDiagnostics, protocol.Diagnostic{
+			Code:     code,
+			Source:   "nomad-ls",
+			Severity: protocol.DiagnosticSeverity(v.Severity),
+			Range: protocol.Range{
+				Start: protocol.Position{
+					Line:      uint32(v.Subject.Start.Line - 1),
+					Character: uint32(v.Subject.Start.Column - 1),
+				},
+				End: protocol.Position{
+					Line:      uint32(v.Subject.End.Line - 1),
+					Character: uint32(v.Subject.End.Column - 1),
+				},
+			},
+			Message: v.Detail,
+		})
+	}
+
+	return protocolDiagnostics
+}
+
+// dependentBodySchema returns the schema of a block whose body depends on the
+// `driver` attribute of the enclosing body, or nil when the driver is missing
+// or not statically known.
+func dependentBodySchema(blockSchema *hclschema.BlockSchema, bodyContent *hcl.BodyContent, ctx *hcl.EvalContext) *hclschema.BodySchema {
+	attr := bodyContent.Attributes["driver"]
+	if attr == nil {
+		return nil
+	}
+
+	driver, diags := attr.Expr.Value(ctx)
+	if diags.HasErrors() || !driver.IsKnown() || driver.IsNull() || driver.Type() != cty.String {
+		return nil
+	}
+
+	return blockSchema.DependentBody[hclschema.SchemaKey(driver.AsString())]
+}
+
 func asBlock(name string, depth int) string {
 	return fmt.Sprintf("%s \"${1:name}\" {\n%s$0\n}", name, strings.Repeat("\t", depth))
 }
@@ -171,6 +329,20 @@ func asAnonymousBlock(name string, depth int) string {
 	return fmt.Sprintf("%s {\n%s$0\n}", name, strings.Repeat("\t", depth))
 }
 
+// protocolRange converts a 1-based hcl range into a 0-based protocol range.
+func protocolRange(r hcl.Range) protocol.Range {
+	return protocol.Range{
+		Start: protocol.Position{
+			Line:      uint32(r.Start.Line - 1),
+			Character: uint32(r.Start.Column - 1),
+		},
+		End: protocol.Position{
+			Line:      uint32(r.End.Line - 1),
+			Character: uint32(r.End.Column - 1),
+		},
+	}
+}
+
 func CalculateByteOffset(pos protocol.Position, src []byte) uint {
 	runes := []rune(string(src))
 
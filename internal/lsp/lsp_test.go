@@ -1,12 +1,24 @@
 package lsp
 
 import (
+	"bytes"
+	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"testing"
 
+	hclschema "github.com/hashicorp/hcl-lang/schema"
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/loczek/nomad-ls/internal/parser"
+	"github.com/loczek/nomad-ls/internal/rules"
+	"github.com/loczek/nomad-ls/internal/schema"
+	"github.com/zclconf/go-cty/cty"
 	"go.lsp.dev/protocol"
 )
 
@@ -15,6 +27,25 @@ const (
 	GENERIC_NOMAD_FILE_PATH           = "./testdata/generic.nomad.hcl"
 	INVALID_ATTRIBUTE_NOMAD_FILE_PATH = "./testdata/invalid_attribute.nomad.hcl"
 	DOCKER_LOGGING_NOMAD_FILE_PATH    = "./testdata/docker_logging.nomad.hcl"
+	TEMPLATE_NOMAD_FILE_PATH          = "./testdata/template.nomad.hcl"
+	MISSING_REQUIRED_NOMAD_FILE_PATH  = "./testdata/missing_required.nomad.hcl"
+	TYPO_NOMAD_FILE_PATH              = "./testdata/typo.nomad.hcl"
+	VARIABLES_NOMAD_FILE_PATH         = "./testdata/variables.nomad.hcl"
+	LOCALS_NOMAD_FILE_PATH            = "./testdata/locals.nomad.hcl"
+	VARIABLE_REFERENCES_FILE_PATH     = "./testdata/variable_references.nomad.hcl"
+	FUNCTIONS_NOMAD_FILE_PATH         = "./testdata/functions.nomad.hcl"
+	NOMAD_FUNCTIONS_NOMAD_FILE_PATH   = "./testdata/nomad_functions.nomad.hcl"
+	PARAMETRISED_NOMAD_FILE_PATH      = "./testdata/parametrised.nomad.hcl"
+	PROD_VARS_FILE_PATH               = "./testdata/prod.vars.hcl"
+	RUNTIME_NOMAD_FILE_PATH           = "./testdata/runtime.nomad.hcl"
+	PORTS_NOMAD_FILE_PATH             = "./testdata/ports.nomad.hcl"
+	VOLUMES_NOMAD_FILE_PATH           = "./testdata/volumes.nomad.hcl"
+	ENUMS_NOMAD_FILE_PATH             = "./testdata/enums.nomad.hcl"
+	FORMATS_NOMAD_FILE_PATH           = "./testdata/formats.nomad.hcl"
+	RULES_NOMAD_FILE_PATH             = "./testdata/rules.nomad.hcl"
+	POLICY_NOMAD_FILE_PATH            = "./testdata/policy.nomad.hcl"
+	CONFIG_DIR_PATH                   = "./testdata/config"
+	SUPPRESSIONS_NOMAD_FILE_PATH      = "./testdata/suppressions.nomad.hcl"
 )
 
 func TestByteCount(t *testing.T) {
@@ -62,7 +93,7 @@ func TestServiceBlockHoverInformation(t *testing.T) {
 				Line:   int(tt.pos.Line),
 				Column: int(tt.pos.Character),
 				Byte:   int(predictedCount),
-			})
+			}, nil)
 
 			t.Logf("blocks: %v", blocks)
 
@@ -86,7 +117,7 @@ func TestBlockCompletion(t *testing.T) {
 		Line:   int(pos.Line),
 		Column: int(pos.Character),
 		Byte:   int(predictedCount),
-	})
+	}, nil)
 
 	t.Logf("blocks: %v", blocks)
 
@@ -98,7 +129,7 @@ func TestBlockCompletion(t *testing.T) {
 func TestMetaBlockAllowsAnyAttribute(t *testing.T) {
 	hclFile := LoadSampleFile(GENERIC_NOMAD_FILE_PATH)
 
-	diags := CollectDiagnostics(hclFile.Body)
+	diags := CollectDiagnostics(hclFile.Body, nil)
 
 	// Filter for errors only (ignore warnings)
 	var errors hcl.Diagnostics
@@ -118,7 +149,7 @@ func TestMetaBlockAllowsAnyAttribute(t *testing.T) {
 func TestDockerLoggingConfigBlock(t *testing.T) {
 	hclFile := LoadSampleFile(DOCKER_LOGGING_NOMAD_FILE_PATH)
 
-	diags := CollectDiagnostics(hclFile.Body)
+	diags := CollectDiagnostics(hclFile.Body, nil)
 
 	// Filter for errors only (ignore warnings)
 	var errors hcl.Diagnostics
@@ -138,7 +169,7 @@ func TestDockerLoggingConfigBlock(t *testing.T) {
 func TestInvalidAttributeGeneratesDiagnostic(t *testing.T) {
 	hclFile := LoadSampleFile(INVALID_ATTRIBUTE_NOMAD_FILE_PATH)
 
-	diags := CollectDiagnostics(hclFile.Body)
+	diags := CollectDiagnostics(hclFile.Body, nil)
 
 	// Filter for errors only
 	var errors hcl.Diagnostics
@@ -167,6 +198,1142 @@ func TestInvalidAttributeGeneratesDiagnostic(t *testing.T) {
 	}
 }
 
+func TestVariableDefinition(t *testing.T) {
+	hclFile := LoadSampleFile(LOKI_NOMAD_FILE_PATH)
+
+	pos := protocol.Position{Line: 11, Character: 20}
+
+	predictedCount := CalculateByteOffset(pos, hclFile.Bytes)
+
+	ranges := CollectDefinitions(hclFile.Body, hcl.Pos{
+		Line:   int(pos.Line),
+		Column: int(pos.Character),
+		Byte:   int(predictedCount),
+	}, map[string]*hcl.File{"nomad-job": hclFile})
+
+	if len(ranges) != 1 {
+		t.Fatalf("expected 1 definition, got: %v", ranges)
+	}
+
+	if ranges[0].Start.Line != 6 {
+		t.Errorf("expected definition on line 6, got: %d", ranges[0].Start.Line)
+	}
+}
+
+func TestVariableRenameInsideTemplate(t *testing.T) {
+	hclFile := LoadSampleFile(GENERIC_NOMAD_FILE_PATH)
+
+	pos := protocol.Position{Line: 1, Character: 4}
+
+	predictedCount := CalculateByteOffset(pos, hclFile.Bytes)
+
+	name, _, ok := FindVariable(hclFile.Body, hcl.Pos{
+		Line:   int(pos.Line),
+		Column: int(pos.Character),
+		Byte:   int(predictedCount),
+	})
+
+	if !ok || name != "app_name" {
+		t.Fatalf("expected variable app_name, got: %q", name)
+	}
+
+	edits := CollectRenameEdits(name, "application", map[string]*hcl.File{"nomad-job": hclFile})["nomad-job"]
+
+	if len(edits) != 2 {
+		t.Fatalf("expected 2 edits, got: %v", edits)
+	}
+
+	if edits[1].Range.Start.Line != 20 || edits[1].Range.Start.Character != 23 {
+		t.Errorf("wrong range for interpolated reference: %v", edits[1].Range)
+	}
+}
+
+func TestDocumentSymbols(t *testing.T) {
+	hclFile := LoadSampleFile(LOKI_NOMAD_FILE_PATH)
+
+	symbols := CollectDocumentSymbols(hclFile.Body, nil)
+
+	if len(symbols) != 3 || symbols[2].Name != "loki" || symbols[2].Detail != "job" {
+		t.Fatalf("unexpected root symbols: %+v", symbols)
+	}
+
+	var group *protocol.DocumentSymbol
+	for i, s := range symbols[2].Children {
+		if s.Detail == "group" {
+			group = &symbols[2].Children[i]
+		}
+	}
+
+	if group == nil {
+		t.Fatal("group symbol not found")
+	}
+
+	var names []string
+	for _, s := range group.Children {
+		if s.Detail == "task" || s.Detail == "service" || s.Detail == "volume" {
+			names = append(names, s.Detail+":"+s.Name)
+		}
+	}
+
+	expected := "service:loki volume:loki-data task:prep-disk task:loki"
+	if strings.Join(names, " ") != expected {
+		t.Errorf("expected %q, got %q", expected, strings.Join(names, " "))
+	}
+}
+
+func TestDocumentSymbolsJSON(t *testing.T) {
+	file, diags := hclparse.NewParser().ParseJSON([]byte(`{"job": {"app": {"group": {"web": {}}}}}`), "job.nomad.json")
+	if diags.HasErrors() {
+		t.Fatal(diags.Error())
+	}
+
+	symbols := CollectDocumentSymbols(file.Body, nil)
+
+	if len(symbols) != 1 || symbols[0].Name != "app" || len(symbols[0].Children) != 1 {
+		t.Errorf("unexpected symbols: %+v", symbols)
+	}
+}
+
+func TestDriverFromVariable(t *testing.T) {
+	src := `variable "driver" {
+  default = "docker"
+}
+
+job "app" {
+  group "app" {
+    task "app" {
+      driver = var.driver
+
+      config {
+        image = "redis"
+
+        logging {
+          type = "journald"
+        }
+      }
+    }
+  }
+}
+`
+
+	file, diags := hclparse.NewParser().ParseHCL([]byte(src), "nomad-job")
+	if diags.HasErrors() {
+		t.Fatal(diags.Error())
+	}
+
+	ctx := NewEvalContext(map[string]*hcl.File{"nomad-job": file}, nil)
+
+	symbols := CollectDocumentSymbols(file.Body, ctx)
+
+	config := symbols[1].Children[0].Children[0].Children[0]
+	if config.Detail != "config" || len(config.Children) != 1 || config.Children[0].Detail != "logging" {
+		t.Errorf("unexpected config symbol: %+v", config)
+	}
+
+	for _, token := range CollectSemanticTokens(file.Body, ctx) {
+		if string(token.Range.SliceBytes(file.Bytes)) == "image" && !slices.Contains(token.Modifiers, protocol.SemanticTokenModifierDefaultLibrary) {
+			t.Errorf("expected the image attribute of the docker config to be known: %+v", token)
+		}
+	}
+}
+
+func TestInlayHintsJSON(t *testing.T) {
+	file, diags := hclparse.NewParser().ParseJSON([]byte(`{"job": {"app": {"group": {"web": {"task": {"web": {"driver": "docker"}}}}}}}`), "job.nomad.json")
+	if diags.HasErrors() {
+		t.Fatal(diags.Error())
+	}
+
+	hints := CollectInlayHints(file.Body, protocol.Range{End: protocol.Position{Line: 1}}, nil)
+
+	if len(hints) != 0 {
+		t.Errorf("unexpected hints: %+v", hints)
+	}
+}
+
+func TestWorkspaceSymbols(t *testing.T) {
+	p := parser.NewParser()
+	p.IndexWorkspace([]string{"./testdata"})
+
+	files := p.WorkspaceFiles()
+
+	if len(files) == 0 {
+		t.Fatal("expected indexed files, got none")
+	}
+
+	symbols := CollectWorkspaceSymbols(files, "PREP")
+
+	if len(symbols) != 1 {
+		t.Fatalf("expected 1 symbol, got: %+v", symbols)
+	}
+
+	if symbols[0].Name != "prep-disk" || symbols[0].ContainerName != "loki.loki" {
+		t.Errorf("unexpected symbol: %+v", symbols[0])
+	}
+
+	if !strings.HasSuffix(symbols[0].Location.URI.Filename(), "loki.nomad.hcl") {
+		t.Errorf("unexpected location: %s", symbols[0].Location.URI)
+	}
+}
+
+func TestSemanticTokens(t *testing.T) {
+	tests := []struct {
+		name         string
+		filePath     string
+		text         string
+		expectedType protocol.SemanticTokenTypes
+	}{
+		{
+			name:         "known attribute",
+			filePath:     INVALID_ATTRIBUTE_NOMAD_FILE_PATH,
+			text:         "datacenters",
+			expectedType: protocol.SemanticTokenProperty,
+		},
+		{
+			name:         "unknown attribute",
+			filePath:     INVALID_ATTRIBUTE_NOMAD_FILE_PATH,
+			text:         "invalid_attribute_that_should_error",
+			expectedType: protocol.SemanticTokenParameter,
+		},
+		{
+			name:         "variable reference",
+			filePath:     LOKI_NOMAD_FILE_PATH,
+			text:         "s3_bucket",
+			expectedType: protocol.SemanticTokenVariable,
+		},
+		{
+			name:         "runtime interpolation",
+			filePath:     LOKI_NOMAD_FILE_PATH,
+			text:         "meta.role",
+			expectedType: protocol.SemanticTokenMacro,
+		},
+		{
+			name:         "function call",
+			filePath:     LOKI_NOMAD_FILE_PATH,
+			text:         "file",
+			expectedType: protocol.SemanticTokenFunction,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hclFile := LoadSampleFile(tt.filePath)
+
+			tokens := CollectSemanticTokens(hclFile.Body, nil)
+
+			found := false
+			for _, token := range tokens {
+				if string(token.Range.SliceBytes(hclFile.Bytes)) == tt.text && token.Type == tt.expectedType {
+					found = true
+				}
+			}
+
+			if !found {
+				t.Errorf("no %s token found for %q", tt.expectedType, tt.text)
+			}
+
+			if len(EncodeSemanticTokens(tokens, nil)) != len(tokens)*5 {
+				t.Errorf("expected every token to be encoded")
+			}
+		})
+	}
+}
+
+func TestFoldingRanges(t *testing.T) {
+	hclFile := LoadSampleFile(TEMPLATE_NOMAD_FILE_PATH)
+
+	ranges := CollectFoldingRanges(hclFile.Body)
+
+	expected := map[[2]uint32]bool{
+		{0, 24}:  false, // job
+		{5, 10}:  false, // config
+		{7, 9}:   false, // args list
+		{13, 21}: false, // template
+		{14, 19}: false, // heredoc
+	}
+
+	for _, r := range ranges {
+		if _, ok := expected[[2]uint32{r.StartLine, r.EndLine}]; ok {
+			expected[[2]uint32{r.StartLine, r.EndLine}] = true
+		}
+	}
+
+	for k, found := range expected {
+		if !found {
+			t.Errorf("missing folding range %v, got: %+v", k, ranges)
+		}
+	}
+}
+
+func TestMissingRequiredAttributeCodeActions(t *testing.T) {
+	hclFile := LoadSampleFile(MISSING_REQUIRED_NOMAD_FILE_PATH)
+
+	var diagnostics []protocol.Diagnostic
+	for _, d := range *CollectDiagnostics(hclFile.Body, nil) {
+		diagnostics = append(diagnostics, protocol.Diagnostic{
+			Range:   protocolRange(*d.Subject),
+			Message: d.Detail,
+		})
+	}
+
+	actions := CollectCodeActions(hclFile, "file:///job.nomad.hcl", diagnostics, nil)
+
+	expected := map[string]string{
+		`Add missing attribute "driver"`:   "\n      driver = \"\"",
+		`Add missing attribute "image"`:    "\n        image = \"default\"\n      ",
+		`Add missing attribute "affinity"`: "\n          affinity = \"none\"\n        ",
+		`Add missing attribute "hook"`:     "\n        hook = \"prestart\"\n      ",
+	}
+
+	if len(actions) != len(expected) {
+		t.Fatalf("expected %d actions, got: %+v", len(expected), actions)
+	}
+
+	for _, action := range actions {
+		edits := action.Edit.Changes["file:///job.nomad.hcl"]
+
+		if len(edits) != 1 || edits[0].NewText != expected[action.Title] {
+			t.Errorf("unexpected edits for %s: %+v", action.Title, edits)
+		}
+	}
+}
+
+func TestEnumDefaults(t *testing.T) {
+	seen := map[*hclschema.BodySchema]bool{}
+
+	var check func(path string, body *hclschema.BodySchema)
+	check = func(path string, body *hclschema.BodySchema) {
+		if body == nil || seen[body] {
+			return
+		}
+		seen[body] = true
+
+		for name, attrSchema := range body.Attributes {
+			values := enumValues(attrSchema.Constraint)
+			if values == nil {
+				continue
+			}
+
+			if val, ok := defaultValue(attrSchema); ok && (val.Type() != cty.String || !matchesEnumValue(val.AsString(), values)) {
+				t.Errorf("default of %s.%s is not one of its values: %#v", path, name, val)
+			}
+		}
+
+		for name, blockSchema := range body.Blocks {
+			check(path+"."+name, blockSchema.Body)
+
+			for key, dependent := range blockSchema.DependentBody {
+				check(fmt.Sprintf("%s.%s[%s]", path, name, key), dependent)
+			}
+		}
+	}
+
+	check("", &schema.RootBodySchema)
+}
+
+func TestClosestNameKeepsCandidates(t *testing.T) {
+	candidates := []string{"upper", "lower", "title"}
+
+	if name := closestName("uper", candidates); name != "upper" {
+		t.Errorf("expected upper, recieved: %s", name)
+	}
+
+	if strings.Join(candidates, " ") != "upper lower title" {
+		t.Errorf("candidates were reordered: %v", candidates)
+	}
+}
+
+func TestDidYouMeanCodeActions(t *testing.T) {
+	hclFile := LoadSampleFile(TYPO_NOMAD_FILE_PATH)
+
+	var diagnostics []protocol.Diagnostic
+	for _, d := range *CollectDiagnostics(hclFile.Body, nil) {
+		diagnostics = append(diagnostics, protocol.Diagnostic{
+			Range:   protocolRange(*d.Subject),
+			Message: d.Detail,
+		})
+	}
+
+	actions := CollectCodeActions(hclFile, "file:///job.nomad.hcl", diagnostics, nil)
+
+	var titles []string
+	for _, action := range actions {
+		titles = append(titles, action.Title)
+	}
+	sort.Strings(titles)
+
+	expected := `Did you mean "group"? Did you mean "health_check"? Did you mean "kill_timeout"?`
+	if strings.Join(titles, " ") != expected {
+		t.Errorf("expected %q, got %q", expected, strings.Join(titles, " "))
+	}
+}
+
+func TestInlayHints(t *testing.T) {
+	hclFile := LoadSampleFile(LOKI_NOMAD_FILE_PATH)
+
+	hints := CollectInlayHints(hclFile.Body, protocol.Range{
+		Start: protocol.Position{Line: 0},
+		End:   protocol.Position{Line: 100},
+	}, NewEvalContext(map[string]*hcl.File{"nomad-job": hclFile}, nil))
+
+	labels := map[uint32]string{}
+	for _, hint := range hints {
+		labels[hint.Position.Line] = hint.Label
+	}
+
+	if labels[75] != `= "grafana/loki:latest"` {
+		t.Errorf("expected default of var.image, got: %q", labels[75])
+	}
+
+	if !strings.Contains(labels[36], "sticky = false") {
+		t.Errorf("expected ephemeral_disk defaults, got: %q", labels[36])
+	}
+}
+
+func TestVariableHover(t *testing.T) {
+	hclFile := LoadSampleFile(LOKI_NOMAD_FILE_PATH)
+
+	pos := protocol.Position{Line: 75, Character: 20}
+
+	predictedCount := CalculateByteOffset(pos, hclFile.Bytes)
+
+	hover := CollectVariableHover(hclFile.Body, hcl.Pos{
+		Line:   int(pos.Line),
+		Column: int(pos.Character),
+		Byte:   int(predictedCount),
+	}, map[string]*hcl.File{"nomad-job": hclFile})
+
+	for _, expected := range []string{"**var.image** `string`", "Default: `\"grafana/loki:latest\"`", "Declared in `nomad-job:1`"} {
+		if !strings.Contains(hover, expected) {
+			t.Errorf("expected %q in hover, got: %q", expected, hover)
+		}
+	}
+}
+
+func TestValueHover(t *testing.T) {
+	hclFile := LoadSampleFile(GENERIC_NOMAD_FILE_PATH)
+
+	pos := protocol.Position{Line: 20, Character: 32}
+
+	predictedCount := CalculateByteOffset(pos, hclFile.Bytes)
+
+	hover := CollectValueHover(hclFile.Body, hcl.Pos{
+		Line:   int(pos.Line),
+		Column: int(pos.Character),
+		Byte:   int(predictedCount),
+	}, NewEvalContext(map[string]*hcl.File{"nomad-job": hclFile}, nil))
+
+	if !strings.Contains(hover, `"example-app:1.0.0"`) {
+		t.Errorf("expected evaluated template in hover, got: %q", hover)
+	}
+}
+
+func TestVariableDiagnostics(t *testing.T) {
+	hclFile := LoadSampleFile(VARIABLES_NOMAD_FILE_PATH)
+
+	var summaries []string
+	for _, d := range *CollectDiagnostics(hclFile.Body, nil) {
+		summaries = append(summaries, d.Summary+" at line "+strconv.Itoa(d.Subject.Start.Line))
+	}
+
+	expected := []string{
+		"Invalid default value for variable at line 19",
+		"Invalid type specification at line 29",
+		"Invalid variable validation condition at line 33",
+	}
+
+	if strings.Join(summaries, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected: %v, recieved: %v", expected, summaries)
+	}
+}
+
+func TestLocalDiagnostics(t *testing.T) {
+	hclFile := LoadSampleFile(LOCALS_NOMAD_FILE_PATH)
+	files := map[string]*hcl.File{LOCALS_NOMAD_FILE_PATH: hclFile}
+
+	diags := *CollectDiagnostics(hclFile.Body, nil)
+	diags = diags.Extend(CollectLocalDiagnostics(hclFile.Body, files))
+
+	var messages []string
+	for _, d := range diags {
+		messages = append(messages, d.Detail)
+	}
+
+	expected := []string{
+		`A local value with the name "prefix" has not been declared.`,
+		`The local value "a" depends on itself: local.a -> local.b -> local.a.`,
+		`The local value "b" depends on itself: local.b -> local.a -> local.b.`,
+	}
+
+	if strings.Join(messages, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected: %v, recieved: %v", expected, messages)
+	}
+}
+
+func TestLocalReferences(t *testing.T) {
+	hclFile := LoadSampleFile(LOCALS_NOMAD_FILE_PATH)
+	files := map[string]*hcl.File{LOCALS_NOMAD_FILE_PATH: hclFile}
+
+	// `local.image` inside the docker config block
+	pos := hcl.Pos{Byte: int(CalculateByteOffset(protocol.Position{Line: 18, Character: 22}, hclFile.Bytes))}
+
+	definitions := CollectDefinitions(hclFile.Body, pos, files)
+	if len(definitions) != 1 || definitions[0].Start.Line != 7 {
+		t.Errorf("expected definition on line 7, recieved: %v", definitions)
+	}
+
+	hover := CollectLocalHover(hclFile.Body, pos, files, NewEvalContext(files, nil))
+	if !strings.Contains(hover, `"nginx:1.25"`) {
+		t.Errorf("expected evaluated value in hover, recieved: %q", hover)
+	}
+
+	src := []byte("image = local.im")
+
+	items, ok := CollectReferenceCompletions(src, len(src), files)
+	if !ok {
+		t.Fatal("expected local completions")
+	}
+
+	var labels []string
+	for _, item := range items {
+		labels = append(labels, item.Label)
+	}
+
+	if strings.Join(labels, ",") != "image,name,a,b" {
+		t.Errorf("expected locals in declaration order, recieved: %v", labels)
+	}
+}
+
+func TestVariableReferenceDiagnostics(t *testing.T) {
+	hclFile := LoadSampleFile(VARIABLE_REFERENCES_FILE_PATH)
+	files := map[string]*hcl.File{VARIABLE_REFERENCES_FILE_PATH: hclFile}
+
+	var messages []string
+	for _, d := range CollectVariableReferenceDiagnostics(hclFile.Body, files) {
+		messages = append(messages, fmt.Sprintf("%d:%d %s", d.Severity, d.Subject.Start.Line, d.Detail))
+	}
+
+	expected := []string{
+		`1:22 An input variable with the name "port" has not been declared.`,
+		`2:6 The variable "unused" is declared but never used.`,
+		`1:12 A variable named "image" was already declared at nomad-job:1. Variable names must be unique.`,
+	}
+
+	if strings.Join(messages, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected: %v, recieved: %v", expected, messages)
+	}
+}
+
+func TestVariableReferenceDiagnosticsOfSiblingJobs(t *testing.T) {
+	parser := hclparse.NewParser()
+
+	a, _ := parser.ParseHCL([]byte("variable \"image\" {}\nvariable \"port\" {}\n\njob \"a\" {\n  meta = { image = var.image }\n}\n"), "a.nomad.hcl")
+	b, _ := parser.ParseHCL([]byte("variable \"image\" {}\n\njob \"b\" {\n  meta = { image = var.image, port = var.port }\n}\n"), "b.nomad.hcl")
+	shared, _ := parser.ParseHCL([]byte("variable \"region\" {}\n"), "variables.nomad.hcl")
+
+	files := map[string]*hcl.File{"a.nomad.hcl": a, "b.nomad.hcl": b, "variables.nomad.hcl": shared}
+
+	var messages []string
+	for _, d := range CollectVariableReferenceDiagnostics(b.Body, scopedFiles("b.nomad.hcl", files)) {
+		messages = append(messages, d.Detail)
+	}
+
+	expected := []string{
+		`An input variable with the name "port" has not been declared.`,
+	}
+
+	if strings.Join(messages, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected: %v, recieved: %v", expected, messages)
+	}
+
+	messages = nil
+	for _, d := range CollectVariableReferenceDiagnostics(a.Body, scopedFiles("a.nomad.hcl", files)) {
+		messages = append(messages, d.Detail)
+	}
+
+	expected = []string{
+		`The variable "port" is declared but never used.`,
+	}
+
+	if strings.Join(messages, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected: %v, recieved: %v", expected, messages)
+	}
+
+	edits := CollectRenameEdits("image", "container_image", scopedFiles("a.nomad.hcl", files))
+	if len(edits) != 1 || len(edits["a.nomad.hcl"]) != 2 {
+		t.Errorf("expected the rename to only edit a.nomad.hcl, recieved: %v", edits)
+	}
+
+	if len(scopedFiles("variables.nomad.hcl", files)) != len(files) {
+		t.Errorf("expected a file without a job to see all its siblings")
+	}
+}
+
+func TestVariableCompletions(t *testing.T) {
+	hclFile := LoadSampleFile(VARIABLES_NOMAD_FILE_PATH)
+	files := map[string]*hcl.File{VARIABLES_NOMAD_FILE_PATH: hclFile}
+
+	tests := []struct {
+		name     string
+		src      string
+		expected []string
+	}{
+		{
+			name: "attribute value",
+			src:  "count = var.",
+			expected: []string{
+				"datacenters: list(string) — The datacenters the job may be placed in.",
+				"resources: object({ cpu = number memory = optional(…",
+				"count: number",
+				"region: strin",
+			},
+		},
+		{
+			name: "template interpolation",
+			src:  "args = [\"--count=${var.co",
+			expected: []string{
+				"datacenters: list(string) — The datacenters the job may be placed in.",
+				"resources: object({ cpu = number memory = optional(…",
+				"count: number",
+				"region: strin",
+			},
+		},
+		{
+			name: "string literal",
+			src:  "image = \"var.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, _ := CollectReferenceCompletions([]byte(tt.src), len(tt.src), files)
+
+			var recieved []string
+			for _, item := range items {
+				recieved = append(recieved, item.Label+": "+item.Detail)
+			}
+
+			if strings.Join(recieved, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("expected: %v, recieved: %v", tt.expected, recieved)
+			}
+		})
+	}
+}
+
+func TestFunctionDiagnostics(t *testing.T) {
+	hclFile := LoadSampleFile(FUNCTIONS_NOMAD_FILE_PATH)
+
+	var messages []string
+	for _, d := range CollectFunctionDiagnostics(hclFile.Body) {
+		messages = append(messages, fmt.Sprintf("%d %d %s", d.Subject.Start.Line, d.Severity, d.Detail))
+	}
+
+	expected := []string{
+		`3 2 There is no function named "uper". Did you mean "upper"?`,
+		`4 1 Function "format" expects 1 argument(s). Missing value for "format".`,
+		`5 1 Function "lower" expects only 1 argument(s).`,
+	}
+
+	sort.Strings(messages)
+
+	if strings.Join(messages, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected: %v, recieved: %v", expected, messages)
+	}
+}
+
+func TestNomadFunctionsDiagnostics(t *testing.T) {
+	hclFile := LoadSampleFile(NOMAD_FUNCTIONS_NOMAD_FILE_PATH)
+	files := map[string]*hcl.File{"nomad-job": hclFile}
+
+	diags := *CollectDiagnostics(hclFile.Body, NewEvalContext(files, nil))
+	diags = diags.Extend(CollectVariableReferenceDiagnostics(hclFile.Body, files))
+	diags = diags.Extend(CollectLocalDiagnostics(hclFile.Body, files))
+
+	for _, d := range diags {
+		t.Errorf("unexpected diagnostic: %s: %s at %v", d.Summary, d.Detail, d.Subject)
+	}
+}
+
+func TestEvalContextFunctionStubs(t *testing.T) {
+	ctx := NewEvalContext(map[string]*hcl.File{}, nil)
+
+	tests := []struct {
+		src      string
+		expected cty.Type
+		known    bool
+	}{
+		{src: `file("job.tpl")`, expected: cty.String},
+		{src: `fileexists("job.tpl")`, expected: cty.Bool},
+		{src: `fileset(".", "*.tpl")`, expected: cty.Set(cty.String)},
+		{src: `uuidv4()`, expected: cty.String},
+		{src: `bcrypt("secret")`, expected: cty.String},
+		{src: `upper("nomad")`, expected: cty.String, known: true},
+	}
+
+	for _, tt := range tests {
+		expr, diags := hclsyntax.ParseExpression([]byte(tt.src), "nomad-job", hcl.InitialPos)
+		if diags.HasErrors() {
+			t.Fatal(diags.Error())
+		}
+
+		val, diags := expr.Value(ctx)
+		if diags.HasErrors() {
+			t.Errorf("%s: unexpected diagnostics: %s", tt.src, diags.Error())
+			continue
+		}
+
+		if !val.Type().Equals(tt.expected) || val.IsKnown() != tt.known {
+			t.Errorf("%s: expected a known=%t %s, recieved: %#v", tt.src, tt.known, tt.expected.FriendlyName(), val)
+		}
+	}
+}
+
+func TestFunctionSignatureHelp(t *testing.T) {
+	tests := []struct {
+		name            string
+		src             string
+		label           string
+		activeParameter uint32
+	}{
+		{
+			name:            "first argument",
+			src:             "args = join(",
+			label:           "join(separator string, lists list of string...)",
+			activeParameter: 0,
+		},
+		{
+			name:            "nested call",
+			src:             "args = join(\" \", [upper(\"a\"), ",
+			label:           "join(separator string, lists list of string...)",
+			activeParameter: 1,
+		},
+		{
+			name:            "template interpolation",
+			src:             "args = \"${format(\"%s\", ",
+			label:           "format(format string, args any...)",
+			activeParameter: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			help := CollectSignatureHelp([]byte(tt.src), len(tt.src))
+			if help == nil {
+				t.Fatal("expected signature help")
+			}
+
+			if help.Signatures[0].Label != tt.label || help.ActiveParameter != tt.activeParameter {
+				t.Errorf("expected: %s %d, recieved: %s %d", tt.label, tt.activeParameter, help.Signatures[0].Label, help.ActiveParameter)
+			}
+		})
+	}
+}
+
+func TestFunctionCompletions(t *testing.T) {
+	src := []byte("args = tri")
+
+	items, ok := CollectFunctionCompletions(src, len(src))
+	if !ok {
+		t.Fatal("expected function completions")
+	}
+
+	var labels []string
+	for _, item := range items {
+		labels = append(labels, item.Label)
+	}
+
+	if strings.Join(labels, ",") != "trim,trimprefix,trimspace,trimsuffix" {
+		t.Errorf("expected trim functions, recieved: %v", labels)
+	}
+
+	src = []byte("  tri")
+	if _, ok := CollectFunctionCompletions(src, len(src)); ok {
+		t.Error("expected no function completions in place of an attribute name")
+	}
+}
+
+func TestParametrisedJobDiagnostics(t *testing.T) {
+	hclFile := LoadSampleFile(PARAMETRISED_NOMAD_FILE_PATH)
+
+	varFile, _ := hclparse.NewParser().ParseHCL([]byte(`driver = "exec"`), "prod.vars.hcl")
+
+	tests := []struct {
+		name     string
+		varFiles []*hcl.File
+		expected []string
+	}{
+		{
+			name: "variable defaults",
+			expected: []string{
+				`An argument named "comand" is not expected here. Did you mean "command"?`,
+				`Inappropriate value for attribute "count": a number is required.`,
+			},
+		},
+		{
+			name:     "var-file",
+			varFiles: []*hcl.File{varFile},
+			expected: []string{
+				`An argument named "comand" is not expected here. Did you mean "command"?`,
+				`An argument named "image" is not expected here.`,
+				`Inappropriate value for attribute "count": a number is required.`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := NewEvalContext(map[string]*hcl.File{"nomad-job": hclFile}, tt.varFiles)
+
+			var messages []string
+			for _, d := range *CollectDiagnostics(hclFile.Body, ctx) {
+				messages = append(messages, d.Detail)
+			}
+
+			sort.Strings(messages)
+
+			if strings.Join(messages, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("expected: %v, recieved: %v", tt.expected, messages)
+			}
+
+			if local := ctx.Variables["local"].GetAttr("name"); !local.IsKnown() {
+				t.Errorf("expected local value to be evaluated with functions")
+			}
+		})
+	}
+}
+
+func TestVarFile(t *testing.T) {
+	varFile := LoadSampleFile(PROD_VARS_FILE_PATH)
+
+	job := parser.VarFileJob(PROD_VARS_FILE_PATH, varFile.Bytes)
+	if job != "testdata/variables.nomad.hcl" {
+		t.Fatalf("expected var-file to be linked to the variables job, recieved: %q", job)
+	}
+
+	jobs := map[string]*hcl.File{job: LoadSampleFile(VARIABLES_NOMAD_FILE_PATH)}
+
+	var messages []string
+	for _, d := range CollectVarFileDiagnostics(varFile.Body, jobs) {
+		messages = append(messages, fmt.Sprintf("%d %s", d.Subject.Start.Line, d.Detail))
+	}
+
+	expected := []string{
+		`3 The value for variable "datacenters" is not compatible with its type constraint: list of string required, but have string.`,
+		`5 A "variable" block with the name "regon" was not found. Did you mean "region"?`,
+	}
+
+	if strings.Join(messages, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected: %v, recieved: %v", expected, messages)
+	}
+
+	var labels []string
+	for _, item := range CollectVarFileCompletions(varFile.Body, jobs) {
+		labels = append(labels, item.Label+": "+item.Detail)
+	}
+
+	if strings.Join(labels, ", ") != "region: any, resources: object" {
+		t.Errorf("expected unset variables, recieved: %v", labels)
+	}
+}
+
+func TestRuntimeDiagnostics(t *testing.T) {
+	file := LoadSampleFile(RUNTIME_NOMAD_FILE_PATH)
+
+	var messages []string
+	for _, d := range CollectRuntimeDiagnostics(file.Body) {
+		messages = append(messages, fmt.Sprintf("%d %s", d.Subject.Start.Line, d.Detail))
+	}
+	sort.Strings(messages)
+
+	expected := []string{
+		`13 There is no variable namespace named "nod", expected ` + "`var`, `local`, `attr`, `node`, `meta`, `env` or a `NOMAD_*` environment variable." + ` Did you mean "node"?`,
+		`14 The client node has no property named "datacentre". Did you mean "datacenter"?`,
+		`28 "NOMAD_TASKDIR" is not an environment variable set by nomad. Did you mean "NOMAD_TASK_DIR"?`,
+	}
+
+	if strings.Join(messages, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected: %v, recieved: %v", expected, messages)
+	}
+}
+
+func TestRuntimeCompletions(t *testing.T) {
+	files := map[string]*hcl.File{"nomad-job": LoadSampleFile(RUNTIME_NOMAD_FILE_PATH)}
+
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{`value = "${node.uni`, "unique.id"},
+		{`value = "${attr.kernel.`, "cpu.arch"},
+		{`value = "${meta.`, "rack"},
+		{`value = "${NOMAD_ME`, "NOMAD_META_team"},
+	}
+
+	for _, test := range tests {
+		items, ok := CollectRuntimeCompletions([]byte(test.src), len(test.src), files)
+		if !ok {
+			t.Fatalf("expected runtime completions for %q", test.src)
+		}
+
+		found := false
+		for _, item := range items {
+			found = found || item.Label == test.expected
+		}
+
+		if !found {
+			t.Errorf("expected %q among the completions for %q", test.expected, test.src)
+		}
+	}
+
+	src := []byte(`value = "node.uni`)
+	if _, ok := CollectRuntimeCompletions(src, len(src), files); ok {
+		t.Error("expected no runtime completions in a string literal")
+	}
+}
+
+func TestPortDiagnostics(t *testing.T) {
+	file := LoadSampleFile(PORTS_NOMAD_FILE_PATH)
+
+	var messages []string
+	for _, d := range CollectPortDiagnostics(file.Body) {
+		r := d.Subject
+		messages = append(messages, fmt.Sprintf("%d %q %s", d.Severity, r.SliceBytes(file.Bytes), d.Detail))
+	}
+
+	expected := []string{
+		`1 "htp" No port labelled "htp" is declared in the network block of group "web". Did you mean "http"?`,
+		`1 "grpc" No port labelled "grpc" is declared in the network block of group "web".`,
+		`4 "debug" The port "debug" is declared but never used.`,
+	}
+
+	if strings.Join(messages, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected: %v, recieved: %v", expected, messages)
+	}
+}
+
+func TestPortCompletions(t *testing.T) {
+	file := LoadSampleFile(PORTS_NOMAD_FILE_PATH)
+
+	for _, marker := range []string{`"${NOMAD_PORT_metrics`, `port = "htp`} {
+		offset := bytes.Index(file.Bytes, []byte(marker)) + len(marker)
+
+		items, ok := CollectPortCompletions(file.Body, file.Bytes, offset)
+		if !ok {
+			t.Fatalf("expected port completions after %s", marker)
+		}
+
+		var labels []string
+		for _, item := range items {
+			labels = append(labels, item.TextEdit.NewText)
+		}
+
+		expected := "admin,debug,http,metrics"
+		if strings.HasPrefix(marker, `"${`) {
+			expected = "NOMAD_PORT_admin,NOMAD_PORT_debug,NOMAD_PORT_http,NOMAD_PORT_metrics"
+		}
+
+		if strings.Join(labels, ",") != expected {
+			t.Errorf("expected %s after %s, recieved: %v", expected, marker, labels)
+		}
+	}
+}
+
+func TestVolumes(t *testing.T) {
+	file := LoadSampleFile(VOLUMES_NOMAD_FILE_PATH)
+
+	var messages []string
+	for _, d := range CollectVolumeDiagnostics(file.Body) {
+		messages = append(messages, fmt.Sprintf("%d %s", d.Subject.Start.Line, d.Detail))
+	}
+
+	expected := []string{
+		`7 The access mode "single-node-writer" requests write access, but the volume is read-only.`,
+		"31 The volume \"certs\" is read-only, so it cannot be mounted with `read_only = false`.",
+		`35 No volume named "cert" is declared in group "db". Did you mean "certs"?`,
+	}
+
+	if strings.Join(messages, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected: %v, recieved: %v", expected, messages)
+	}
+
+	offset := bytes.Index(file.Bytes, []byte(`"cert"`)) + 2
+
+	items, ok := CollectVolumeCompletions(file.Body, offset)
+	if !ok || len(items) != 2 || items[0].Label != "certs" || items[1].Label != "data" {
+		t.Errorf("expected the group volumes to be completed, recieved: %v", items)
+	}
+
+	definitions := CollectDefinitions(file.Body, hcl.Pos{Byte: bytes.Index(file.Bytes, []byte(`= "data"`)) + 4}, nil)
+	if len(definitions) != 1 || definitions[0].Start.Line != 3 {
+		t.Errorf("expected the definition of the data volume, recieved: %v", definitions)
+	}
+}
+
+func TestEnumeratedValues(t *testing.T) {
+	file := LoadSampleFile(ENUMS_NOMAD_FILE_PATH)
+
+	var messages []string
+	diags := *CollectDiagnostics(file.Body, nil)
+	sort.Slice(diags, func(i, j int) bool {
+		return diags[i].Subject.Start.Line < diags[j].Subject.Start.Line
+	})
+
+	for _, d := range diags {
+		messages = append(messages, fmt.Sprintf("%d %s", d.Subject.Start.Line, d.Detail))
+	}
+
+	expected := []string{
+		`2 Invalid value for attribute "type", expected one of "service", "batch", "system", "sysbatch". Did you mean "service"?`,
+		`27 Invalid value for attribute "hook", expected one of "prestart", "poststart", "poststop". Did you mean "prestart"?`,
+	}
+
+	if strings.Join(messages, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected: %v, recieved: %v", expected, messages)
+	}
+
+	offset := bytes.Index(file.Bytes, []byte(`"fail"`)) + 2
+
+	var labels []string
+	for _, item := range CollectCompletions(file.Body, hcl.Pos{Byte: offset}, nil) {
+		labels = append(labels, item.TextEdit.NewText)
+	}
+
+	if strings.Join(labels, ",") != "delay,fail" {
+		t.Errorf("expected the restart modes to be completed, recieved: %v", labels)
+	}
+}
+
+func TestValueFormats(t *testing.T) {
+	file := LoadSampleFile(FORMATS_NOMAD_FILE_PATH)
+
+	diags := *CollectDiagnostics(file.Body, nil)
+	sort.Slice(diags, func(i, j int) bool {
+		return diags[i].Subject.Start.Byte < diags[j].Subject.Start.Byte
+	})
+
+	var messages []string
+	for _, d := range diags {
+		messages = append(messages, fmt.Sprintf("%s %q", d.Summary, d.Subject.SliceBytes(file.Bytes)))
+	}
+
+	expected := []string{
+		`Invalid cron expression "25"`,
+		`Invalid cron expression "@dayly"`,
+		`Invalid time zone "\"Mars/Olympus\""`,
+		`Invalid size "1.5"`,
+		`Invalid duration "\"5 seconds\""`,
+		`Invalid duration "\"5x\""`,
+	}
+
+	if strings.Join(messages, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected: %v, recieved: %v", expected, messages)
+	}
+}
+
+func TestRules(t *testing.T) {
+	file := LoadSampleFile(RULES_NOMAD_FILE_PATH)
+
+	diags := rules.Run(file.Body, nil, rules.Default)
+	sort.SliceStable(diags, func(i, j int) bool {
+		return diags[i].Subject.Start.Byte < diags[j].Subject.Start.Byte
+	})
+
+	var messages []string
+	for _, d := range diags {
+		messages = append(messages, fmt.Sprintf("%s %q", rules.ID(d), d.Subject.SliceBytes(file.Bytes)))
+	}
+
+	expected := []string{
+		`periodic-job-type "periodic"`,
+		`canary-promotion "2"`,
+		`update-deadlines "\"10m\""`,
+		`scaling-count "5"`,
+		`update-deadlines "\"5m\""`,
+		`update-deadlines "\"4m\""`,
+		`scaling-count "3"`,
+		`batch-job-update "update"`,
+		`scaling-count "0"`,
+	}
+
+	if strings.Join(messages, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected: %v, recieved: %v", expected, messages)
+	}
+}
+
+func TestRulesConfig(t *testing.T) {
+	config, filename, diags := rules.LoadConfig(CONFIG_DIR_PATH)
+	if diags.HasErrors() || filepath.Base(filename) != ".nomad-ls.hcl" {
+		t.Fatalf("failed to load %q: %s", filename, diags.Error())
+	}
+
+	file := LoadSampleFile(POLICY_NOMAD_FILE_PATH)
+
+	diags = rules.Run(file.Body, nil, config.Rules())
+	sort.SliceStable(diags, func(i, j int) bool {
+		return diags[i].Subject.Start.Byte < diags[j].Subject.Start.Byte
+	})
+
+	var messages []string
+	for _, d := range diags {
+		messages = append(messages, fmt.Sprintf("%s %d %q", rules.ID(d), d.Severity, d.Subject.SliceBytes(file.Bytes)))
+	}
+
+	expected := []string{
+		fmt.Sprintf(`allowed-datacenters %d "\"us-east-1\""`, hcl.DiagError),
+		fmt.Sprintf(`required-meta %d "meta"`, hcl.DiagWarning),
+		fmt.Sprintf(`scaling-count %d "4"`, hcl.DiagWarning),
+		fmt.Sprintf(`max-memory %d "4096"`, hcl.DiagError),
+	}
+
+	if strings.Join(messages, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected: %v, recieved: %v", expected, messages)
+	}
+
+	_, diags = rules.ParseConfig([]byte(`{"rule": {"update-deadlines": {"severity": "fatal"}}}`), ".nomad-ls.json")
+	if len(diags) != 1 || diags[0].Summary != "Invalid severity" {
+		t.Errorf("expected an invalid severity, recieved: %v", diags)
+	}
+}
+
+func TestSuppressions(t *testing.T) {
+	file := LoadSampleFile(SUPPRESSIONS_NOMAD_FILE_PATH)
+
+	diags := CollectVariableReferenceDiagnostics(file.Body, map[string]*hcl.File{"nomad-job": file})
+	diags = diags.Extend(CollectFunctionDiagnostics(file.Body))
+	diags = diags.Extend(rules.Run(file.Body, nil, rules.Default))
+
+	filtered := FilterSuppressedDiagnostics(file, diags, nil)
+	sort.SliceStable(filtered, func(i, j int) bool {
+		return filtered[i].Subject.Start.Byte < filtered[j].Subject.Start.Byte
+	})
+
+	var messages []string
+	for _, d := range filtered {
+		messages = append(messages, fmt.Sprintf("%s %d:%d %s", rules.ID(d), d.Subject.Start.Line, d.Subject.End.Column, d.Detail))
+	}
+
+	expected := []string{
+		`unused-suppression 1:42 No diagnostic of the rule "duplicate-variable" is suppressed by this comment.`,
+		`unused-variable 8:17 The variable "unused" is declared but never used.`,
+		`unused-suppression 13:57 No diagnostic of the rule "unused-variable" is suppressed by this comment.`,
+	}
+
+	if strings.Join(messages, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected: %v, recieved: %v", expected, messages)
+	}
+
+	protocolDiagnostics := asProtocolDiagnostics(filtered)
+	if protocolDiagnostics[1].Code != "unused-variable" {
+		t.Errorf("expected the rule ID as code, recieved: %v", protocolDiagnostics[1].Code)
+	}
+
+	config, _ := rules.ParseConfig([]byte(`rule "unused-variable" { enabled = false }`), ".nomad-ls.hcl")
+
+	filtered = config.Apply(FilterSuppressedDiagnostics(file, diags, config))
+	if len(filtered) != 1 || !strings.Contains(filtered[0].Detail, "duplicate-variable") {
+		t.Errorf("expected no diagnostics of the disabled rule, recieved: %v", filtered)
+	}
+}
+
 func LoadSampleFile(path string) *hcl.File {
 	parser := hclparse.NewParser()
 
@@ -0,0 +1,368 @@
+package lsp
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/loczek/nomad-ls/internal/rules"
+	"github.com/zclconf/go-cty/cty"
+	"go.lsp.dev/protocol"
+)
+
+// portEnvPrefixes are the prefixes of the environment variables nomad sets
+// for every port label declared in the network block of a group.
+var portEnvPrefixes = []string{
+	"NOMAD_ADDR_",
+	"NOMAD_ALLOC_ADDR_",
+	"NOMAD_ALLOC_IP_",
+	"NOMAD_ALLOC_PORT_",
+	"NOMAD_HOST_ADDR_",
+	"NOMAD_HOST_IP_",
+	"NOMAD_HOST_PORT_",
+	"NOMAD_IP_",
+	"NOMAD_PORT_",
+}
+
+// portEnvPattern matches the port environment variables mentioned in string
+// literals, such as `{{ env "NOMAD_PORT_http" }}` in a template.
+var portEnvPattern = regexp.MustCompile(`NOMAD_(?:ALLOC_|HOST_)?(?:ADDR|IP|PORT)_([A-Za-z0-9_-]+)`)
+
+// portAttributes are the attributes, by the type of their enclosing block,
+// whose values are port labels.
+var portAttributes = map[string]string{
+	"check":           "port",
+	"config":          "ports",
+	"service":         "port",
+	"sidecar_service": "port",
+}
+
+// connectPortPrefix starts the labels of the ports nomad allocates for
+// Consul Connect proxies and gateways, which are never declared.
+const connectPortPrefix = "connect-"
+
+// portReference is a use of a port label, either in an attribute such as
+// `service.port` or in an environment variable such as `NOMAD_PORT_http`.
+type portReference struct {
+	Label string
+	Range hcl.Range
+}
+
+// portGroup holds the port labels declared in the network blocks of a group
+// and the references to port labels within the group.
+type portGroup struct {
+	Block      *hclsyntax.Block
+	Ports      map[string]hcl.Range
+	References []portReference
+
+	// mentions are the labels found in the port environment variables of
+	// string literals, which count as uses but are not validated.
+	mentions map[string]bool
+}
+
+// Name returns the label of the group.
+func (g portGroup) Name() string {
+	return blockLabel(g.Block)
+}
+
+// collectPortGroups returns the port labels and references of every group of
+// the jobs in body.
+func collectPortGroups(body hcl.Body) []portGroup {
+	var groups []portGroup
+
+	for _, group := range jobGroups(body) {
+		g := portGroup{
+			Block:    group,
+			Ports:    map[string]hcl.Range{},
+			mentions: map[string]bool{},
+		}
+
+		collectPorts(group.Body, "group", &g)
+
+		groups = append(groups, g)
+	}
+
+	return groups
+}
+
+// blockLabel returns the first label of b, or an empty string if it has none.
+func blockLabel(b *hclsyntax.Block) string {
+	if len(b.Labels) == 0 {
+		return ""
+	}
+
+	return b.Labels[0]
+}
+
+// jobGroups returns the group blocks of every job in body.
+func jobGroups(body hcl.Body) []*hclsyntax.Block {
+	var groups []*hclsyntax.Block
+
+	syntaxBody, ok := body.(*hclsyntax.Body)
+	if !ok {
+		return groups
+	}
+
+	for _, job := range syntaxBody.Blocks {
+		if job.Type != "job" {
+			continue
+		}
+
+		for _, group := range job.Body.Blocks {
+			if group.Type == "group" {
+				groups = append(groups, group)
+			}
+		}
+	}
+
+	return groups
+}
+
+func collectPorts(body *hclsyntax.Body, blockType string, g *portGroup) {
+	for _, attr := range sortedSyntaxAttributes(body.Attributes) {
+		if portAttributes[blockType] == attr.Name {
+			g.References = append(g.References, portLabelReferences(attr.Expr)...)
+		}
+
+		for _, traversal := range attr.Expr.Variables() {
+			root := traversal.RootName()
+
+			for _, prefix := range portEnvPrefixes {
+				if !strings.HasPrefix(root, prefix) || len(root) == len(prefix) {
+					continue
+				}
+
+				r := traversal[0].SourceRange()
+				r.Start.Byte += len(prefix)
+				r.Start.Column += len(prefix)
+
+				g.References = append(g.References, portReference{
+					Label: strings.TrimPrefix(root, prefix),
+					Range: r,
+				})
+			}
+		}
+
+		hclsyntax.VisitAll(attr.Expr, func(node hclsyntax.Node) hcl.Diagnostics {
+			if lit, ok := node.(*hclsyntax.LiteralValueExpr); ok && lit.Val.Type() == cty.String && lit.Val.IsKnown() && !lit.Val.IsNull() {
+				for _, match := range portEnvPattern.FindAllStringSubmatch(lit.Val.AsString(), -1) {
+					g.mentions[match[1]] = true
+				}
+			}
+			return nil
+		})
+	}
+
+	for _, b := range body.Blocks {
+		if b.Type == "network" && (blockType == "group" || blockType == "resources") {
+			for _, port := range b.Body.Blocks {
+				if port.Type == "port" && len(port.Labels) > 0 {
+					g.Ports[port.Labels[0]] = labelNameRange(port.Labels[0], port.LabelRanges[0])
+				}
+			}
+		}
+
+		collectPorts(b.Body, b.Type, g)
+	}
+}
+
+// portLabelReferences returns the port labels set statically by expr, which
+// is a string or a list of strings. Numeric ports are not labels.
+func portLabelReferences(expr hclsyntax.Expression) []portReference {
+	var exprs []hclsyntax.Expression
+
+	if tuple, ok := expr.(*hclsyntax.TupleConsExpr); ok {
+		exprs = tuple.Exprs
+	} else {
+		exprs = []hclsyntax.Expression{expr}
+	}
+
+	var references []portReference
+
+	for _, e := range exprs {
+		val, diags := e.Value(nil)
+		if diags.HasErrors() || val.Type() != cty.String || !val.IsKnown() || val.IsNull() {
+			continue
+		}
+
+		label := val.AsString()
+		if _, err := strconv.Atoi(label); err == nil || label == "" {
+			continue
+		}
+
+		references = append(references, portReference{
+			Label: label,
+			Range: labelNameRange(label, e.Range()),
+		})
+	}
+
+	return references
+}
+
+// CollectPortDiagnostics cross-checks the port labels used by every group in
+// body with the ports declared in its network block. Undeclared labels are
+// errors, while declared ports that are never used are hints.
+func CollectPortDiagnostics(body hcl.Body) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+
+	for _, g := range collectPortGroups(body) {
+		labels := mapKeys(g.Ports)
+		sort.Strings(labels)
+
+		used := map[string]bool{}
+
+		for _, ref := range g.References {
+			used[ref.Label] = true
+
+			if _, ok := g.Ports[ref.Label]; ok || strings.HasPrefix(ref.Label, connectPortPrefix) {
+				continue
+			}
+
+			detail := fmt.Sprintf("No port labelled %q is declared in the network block of group %q.", ref.Label, g.Name())
+			if suggestion := closestName(ref.Label, labels); suggestion != "" {
+				detail += fmt.Sprintf(" Did you mean %q?", suggestion)
+			}
+
+			diags = diags.Append(&hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Undefined port label",
+				Detail:   detail,
+				Subject:  ref.Range.Ptr(),
+				Extra:    rules.DiagnosticExtra("undefined-port"),
+			})
+		}
+
+		for _, label := range labels {
+			if used[label] || g.mentions[label] {
+				continue
+			}
+
+			r := g.Ports[label]
+
+			diags = diags.Append(&hcl.Diagnostic{
+				Severity: DiagHint,
+				Summary:  "Unused port",
+				Detail:   fmt.Sprintf("The port %q is declared but never used.", label),
+				Subject:  r.Ptr(),
+				Extra:    rules.DiagnosticExtra("unused-port"),
+			})
+		}
+	}
+
+	return diags
+}
+
+// CollectPortCompletions completes the port labels declared in the group
+// enclosing offset, either within a port environment variable such as
+// `NOMAD_PORT_ht` or within the value of an attribute such as
+// `service.port`. The boolean result is false when no port label is expected
+// at offset.
+func CollectPortCompletions(body hcl.Body, src []byte, offset int) ([]protocol.CompletionItem, bool) {
+	var group *portGroup
+
+	groups := collectPortGroups(body)
+	for i := range groups {
+		r := groups[i].Block.Range()
+		if r.Start.Byte <= offset && offset <= r.End.Byte {
+			group = &groups[i]
+		}
+	}
+
+	if group == nil {
+		return nil, false
+	}
+
+	var prefix string
+	var r protocol.Range
+
+	if ref, ok := stringValueAt(group.Block.Body, "group", portAttributes, offset); ok {
+		r = protocolRange(ref)
+	} else {
+		name := runtimePrefix(src, offset)
+		if strings.Contains(name, ".") || !inExpression(src, offset) {
+			return nil, false
+		}
+
+		for _, p := range portEnvPrefixes {
+			if strings.HasPrefix(name, p) {
+				prefix = p
+			}
+		}
+
+		if prefix == "" {
+			return nil, false
+		}
+
+		r = protocol.Range{
+			Start: protocolPosition(src, offset-len(name)),
+			End:   protocolPosition(src, offset),
+		}
+	}
+
+	labels := mapKeys(group.Ports)
+	sort.Strings(labels)
+
+	items := []protocol.CompletionItem{}
+
+	for _, label := range labels {
+		items = append(items, protocol.CompletionItem{
+			Label:  prefix + label,
+			Kind:   protocol.CompletionItemKindVariable,
+			Detail: fmt.Sprintf("Port of group %q", group.Name()),
+			TextEdit: &protocol.TextEdit{
+				Range:   r,
+				NewText: prefix + label,
+			},
+		})
+	}
+
+	return items, true
+}
+
+// stringValueAt returns the range of the string, without its quotes, under
+// offset in the value of one of attributes within body, the body of a
+// blockType block. Attributes are keyed by the type of their enclosing block
+// and their value is either a string or a list of strings.
+func stringValueAt(body *hclsyntax.Body, blockType string, attributes map[string]string, offset int) (hcl.Range, bool) {
+	if name, ok := attributes[blockType]; ok {
+		if attr := body.Attributes[name]; attr != nil {
+			exprs := []hclsyntax.Expression{attr.Expr}
+			if tuple, ok := attr.Expr.(*hclsyntax.TupleConsExpr); ok {
+				exprs = tuple.Exprs
+			}
+
+			for _, e := range exprs {
+				r := e.Range()
+				if _, ok := e.(*hclsyntax.TemplateExpr); ok && r.Start.Byte < offset && offset < r.End.Byte {
+					r.Start.Byte += 1
+					r.Start.Column += 1
+					r.End.Byte -= 1
+					r.End.Column -= 1
+
+					return r, true
+				}
+			}
+		}
+	}
+
+	for _, b := range body.Blocks {
+		if r, ok := stringValueAt(b.Body, b.Type, attributes, offset); ok {
+			return r, true
+		}
+	}
+
+	return hcl.Range{}, false
+}
+
+func mapKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	return keys
+}
@@ -0,0 +1,48 @@
+package lsp
+
+import "go.lsp.dev/protocol"
+
+// This file holds the parts of the language server protocol that are missing
+// from the go.lsp.dev/protocol package, which implements LSP 3.16.
+
+const MethodTextDocumentInlayHint = "textDocument/inlayHint"
+
+type InitializeResult struct {
+	Capabilities ServerCapabilities   `json:"capabilities"`
+	ServerInfo   *protocol.ServerInfo `json:"serverInfo,omitempty"`
+}
+
+type ServerCapabilities struct {
+	protocol.ServerCapabilities
+
+	InlayHintProvider any `json:"inlayHintProvider,omitempty"`
+}
+
+// semanticTokensOptions is the server capability for semantic tokens, which
+// protocol.SemanticTokensOptions is missing the legend and flags for.
+type semanticTokensOptions struct {
+	Legend protocol.SemanticTokensLegend `json:"legend"`
+	Range  bool                          `json:"range"`
+	Full   bool                          `json:"full"`
+}
+
+type InlayHintParams struct {
+	TextDocument protocol.TextDocumentIdentifier `json:"textDocument"`
+	Range        protocol.Range                  `json:"range"`
+}
+
+type InlayHintKind uint32
+
+const (
+	InlayHintKindType      InlayHintKind = 1
+	InlayHintKindParameter InlayHintKind = 2
+)
+
+type InlayHint struct {
+	Position     protocol.Position `json:"position"`
+	Label        string            `json:"label"`
+	Kind         InlayHintKind     `json:"kind,omitempty"`
+	Tooltip      string            `json:"tooltip,omitempty"`
+	PaddingLeft  bool              `json:"paddingLeft,omitempty"`
+	PaddingRight bool              `json:"paddingRight,omitempty"`
+}
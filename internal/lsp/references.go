@@ -0,0 +1,64 @@
+package lsp
+
+import (
+	"github.com/hashicorp/hcl/v2"
+	"go.lsp.dev/protocol"
+)
+
+// FindVariable returns the name of the input variable referenced or declared
+// under pos, along with the range covering just the name.
+func FindVariable(body hcl.Body, pos hcl.Pos) (string, hcl.Range, bool) {
+	if traversal := FindTraversal(body, pos); traversal != nil && traversal.RootName() == "var" {
+		return traversalName(traversal)
+	}
+
+	for _, v := range CollectVariables(body) {
+		if v.NameRange.ContainsPos(pos) {
+			return v.Name, v.NameRange, true
+		}
+	}
+
+	return "", hcl.Range{}, false
+}
+
+// CollectReferences returns the name ranges of every `var.<name>` reference in
+// files, including references inside template interpolations. Declarations
+// of the variable are included when includeDeclaration is set.
+func CollectReferences(name string, files map[string]*hcl.File, includeDeclaration bool) []hcl.Range {
+	var ranges []hcl.Range
+
+	for _, filename := range sortedFilenames(files) {
+		body := files[filename].Body
+
+		if includeDeclaration {
+			for _, v := range CollectVariables(body) {
+				if v.Name == name {
+					ranges = append(ranges, v.NameRange)
+				}
+			}
+		}
+
+		for _, traversal := range CollectTraversals(body, "var") {
+			if n, r, ok := traversalName(traversal); ok && n == name {
+				ranges = append(ranges, r)
+			}
+		}
+	}
+
+	return ranges
+}
+
+// CollectRenameEdits returns the edits, grouped by filename, renaming the
+// input variable name to newName in both its declarations and references.
+func CollectRenameEdits(name string, newName string, files map[string]*hcl.File) map[string][]protocol.TextEdit {
+	edits := map[string][]protocol.TextEdit{}
+
+	for _, r := range CollectReferences(name, files, true) {
+		edits[r.Filename] = append(edits[r.Filename], protocol.TextEdit{
+			Range:   protocolRange(r),
+			NewText: newName,
+		})
+	}
+
+	return edits
+}
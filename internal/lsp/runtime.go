@@ -0,0 +1,509 @@
+package lsp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/loczek/nomad-ls/internal/rules"
+	"go.lsp.dev/protocol"
+)
+
+// runtimeVariable is a variable interpolated by nomad when placing or running
+// an allocation, such as `${node.datacenter}` or `${NOMAD_ALLOC_ID}`.
+type runtimeVariable struct {
+	Name        string
+	Description string
+}
+
+// runtimeNamespaces are the roots of the references interpolated by nomad at
+// runtime, alongside the `NOMAD_*` environment variables.
+var runtimeNamespaces = []string{"attr", "env", "meta", "node"}
+
+// runtimeNodeVariables are the properties of the client node available as
+// `${node.*}`.
+var runtimeNodeVariables = []runtimeVariable{
+	{"unique.id", "The 36 character unique client node identifier."},
+	{"unique.name", "The name of the client node."},
+	{"datacenter", "The datacenter of the client node."},
+	{"region", "The region of the client node."},
+	{"class", "The class of the client node."},
+	{"pool", "The node pool of the client node."},
+}
+
+// runtimeAttributes are the common fingerprinted attributes of a client node
+// available as `${attr.*}`. Drivers and devices add attributes of their own,
+// so any other attribute is allowed.
+var runtimeAttributes = []runtimeVariable{
+	{"cpu.arch", "The CPU architecture of the client, e.g. `amd64`."},
+	{"cpu.frequency", "The CPU frequency in MHz."},
+	{"cpu.modelname", "The model name of the CPU."},
+	{"cpu.numcores", "The number of CPU cores."},
+	{"cpu.totalcompute", "The number of CPU cores multiplied by the CPU frequency."},
+	{"kernel.arch", "The kernel architecture of the client, e.g. `x86_64`."},
+	{"kernel.name", "The kernel of the client, e.g. `linux`."},
+	{"kernel.version", "The version of the kernel of the client."},
+	{"memory.totalbytes", "The amount of memory of the client in bytes."},
+	{"os.name", "The name of the operating system, e.g. `ubuntu`."},
+	{"os.signals", "The signals supported by the operating system."},
+	{"os.version", "The version of the operating system."},
+	{"unique.hostname", "The hostname of the client."},
+	{"unique.network.ip-address", "The IP address fingerprinted by the client."},
+	{"unique.storage.bytesfree", "The free bytes of the volume holding the data directory."},
+	{"unique.storage.bytestotal", "The size in bytes of the volume holding the data directory."},
+	{"unique.storage.volume", "The volume holding the data directory."},
+	{"nomad.advertise.address", "The address the client advertises."},
+	{"nomad.revision", "The git revision of the nomad agent."},
+	{"nomad.version", "The version of the nomad agent."},
+	{"consul.datacenter", "The datacenter of the local consul agent."},
+	{"consul.version", "The version of the local consul agent."},
+	{"vault.accessible", "Whether vault is accessible from the client."},
+	{"vault.version", "The version of vault."},
+	{"driver.docker", "Set when the docker driver is available."},
+	{"driver.docker.version", "The version of docker."},
+	{"driver.exec", "Set when the exec driver is available."},
+	{"driver.java", "Set when the java driver is available."},
+	{"driver.java.version", "The version of java."},
+	{"driver.qemu", "Set when the qemu driver is available."},
+	{"driver.raw_exec", "Set when the raw_exec driver is available."},
+	{"platform.aws.ami-id", "The AMI of the AWS instance."},
+	{"platform.aws.instance-type", "The type of the AWS instance."},
+	{"platform.aws.placement.availability-zone", "The availability zone of the AWS instance."},
+	{"unique.platform.aws.hostname", "The hostname of the AWS instance."},
+	{"unique.platform.aws.instance-id", "The identifier of the AWS instance."},
+	{"unique.platform.aws.local-ipv4", "The private IPv4 address of the AWS instance."},
+}
+
+// runtimeEnvVariables are the environment variables nomad sets in every task.
+var runtimeEnvVariables = []runtimeVariable{
+	{"NOMAD_ALLOC_DIR", "The path to the shared alloc directory."},
+	{"NOMAD_ALLOC_ID", "The allocation ID of the task."},
+	{"NOMAD_ALLOC_INDEX", "The index of the allocation within the task group."},
+	{"NOMAD_ALLOC_NAME", "The allocation name of the task."},
+	{"NOMAD_CPU_CORES", "The specific CPU cores reserved for the task."},
+	{"NOMAD_CPU_LIMIT", "The CPU limit of the task in MHz."},
+	{"NOMAD_DC", "The datacenter in which the allocation is running."},
+	{"NOMAD_GROUP_NAME", "The name of the task group."},
+	{"NOMAD_JOB_ID", "The ID of the job."},
+	{"NOMAD_JOB_NAME", "The name of the job."},
+	{"NOMAD_JOB_PARENT_ID", "The ID of the parent job of a dispatched or periodic job."},
+	{"NOMAD_MEMORY_LIMIT", "The memory limit of the task in MB."},
+	{"NOMAD_MEMORY_MAX_LIMIT", "The maximum memory limit of the task in MB."},
+	{"NOMAD_NAMESPACE", "The namespace in which the allocation is running."},
+	{"NOMAD_PARENT_CGROUP", "The parent cgroup of the task."},
+	{"NOMAD_REGION", "The region in which the allocation is running."},
+	{"NOMAD_SECRETS_DIR", "The path to the secrets directory of the task."},
+	{"NOMAD_SHORT_ALLOC_ID", "The first 8 characters of the allocation ID."},
+	{"NOMAD_TASK_DIR", "The path to the local directory of the task."},
+	{"NOMAD_TASK_NAME", "The name of the task."},
+	{"NOMAD_TOKEN", "The workload identity token of the task."},
+	{"NOMAD_UNIX_ADDR", "The path to the unix socket of the task API."},
+}
+
+// runtimeEnvPrefixes are the environment variables nomad sets for every port
+// label, meta key or upstream, which follows the prefix.
+var runtimeEnvPrefixes = []runtimeVariable{
+	{"NOMAD_ADDR_", "The `host:port` pair of the port label."},
+	{"NOMAD_ALLOC_ADDR_", "The `host:port` pair of the port label within the allocation network namespace."},
+	{"NOMAD_ALLOC_IP_", "The IP of the port label within the allocation network namespace."},
+	{"NOMAD_ALLOC_PORT_", "The port of the port label within the allocation network namespace."},
+	{"NOMAD_ENVOY_ADMIN_ADDR_", "The address of the envoy admin interface of the service."},
+	{"NOMAD_ENVOY_READY_ADDR_", "The address of the envoy readiness check of the service."},
+	{"NOMAD_HOST_ADDR_", "The `host:port` pair of the port label on the host."},
+	{"NOMAD_HOST_IP_", "The host IP of the port label."},
+	{"NOMAD_HOST_PORT_", "The port of the port label on the host."},
+	{"NOMAD_IP_", "The IP of the port label."},
+	{"NOMAD_META_", "The value of the meta key."},
+	{"NOMAD_PORT_", "The port of the port label."},
+	{"NOMAD_UPSTREAM_ADDR_", "The `host:port` pair of the connect upstream."},
+	{"NOMAD_UPSTREAM_IP_", "The IP of the connect upstream."},
+	{"NOMAD_UPSTREAM_PORT_", "The port of the connect upstream."},
+}
+
+// runtimeEnvPrefix returns the prefix of runtimeEnvPrefixes that name starts
+// with, e.g. `NOMAD_PORT_` for `NOMAD_PORT_http`.
+func runtimeEnvPrefix(name string) (string, bool) {
+	for _, prefix := range runtimeEnvPrefixes {
+		if strings.HasPrefix(name, prefix.Name) && len(name) > len(prefix.Name) {
+			return prefix.Name, true
+		}
+	}
+
+	return "", false
+}
+
+// isRuntimeEnvVariable reports whether name is an environment variable set
+// by nomad.
+func isRuntimeEnvVariable(name string) bool {
+	for _, v := range runtimeEnvVariables {
+		if v.Name == name {
+			return true
+		}
+	}
+
+	_, ok := runtimeEnvPrefix(name)
+
+	return ok
+}
+
+// CollectRuntimeDiagnostics validates the references to runtime variables in
+// body. References whose root is neither a runtime namespace, a `NOMAD_*`
+// environment variable, `var` nor `local` are errors, while unknown node
+// properties and environment variables are warnings.
+func CollectRuntimeDiagnostics(body hcl.Body) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+
+	syntaxBody, ok := body.(*hclsyntax.Body)
+	if !ok {
+		return diags
+	}
+
+	for _, traversal := range runtimeTraversals(syntaxBody, "", nil) {
+		diags = diags.Extend(checkRuntimeTraversal(traversal))
+	}
+
+	return diags
+}
+
+// runtimeTraversals returns the root traversals of the expressions in body,
+// the body of a blockType block, leaving out the iterators of `for`
+// expressions and of the dynamic blocks in scope as well as type expressions,
+// either of variables or passed to functions such as `convert`.
+func runtimeTraversals(body *hclsyntax.Body, blockType string, iterators []string) []hcl.Traversal {
+	var traversals []hcl.Traversal
+
+	for _, attr := range sortedSyntaxAttributes(body.Attributes) {
+		if blockType == "variable" && attr.Name == "type" {
+			continue
+		}
+
+		typeRanges := typeArgumentRanges(attr.Expr)
+
+		for _, traversal := range attr.Expr.Variables() {
+			if !contains(iterators, traversal.RootName()) && !containsRange(typeRanges, traversal.SourceRange()) {
+				traversals = append(traversals, traversal)
+			}
+		}
+	}
+
+	for _, b := range body.Blocks {
+		scope := iterators
+
+		if b.Type == "dynamic" && len(b.Labels) > 0 {
+			iterator := b.Labels[0]
+			if attr := b.Body.Attributes["iterator"]; attr != nil {
+				if name := hcl.ExprAsKeyword(attr.Expr); name != "" {
+					iterator = name
+				}
+			}
+
+			scope = append(append([]string{}, iterators...), iterator)
+		}
+
+		traversals = append(traversals, runtimeTraversals(b.Body, b.Type, scope)...)
+	}
+
+	return traversals
+}
+
+func checkRuntimeTraversal(traversal hcl.Traversal) hcl.Diagnostics {
+	root := traversal.RootName()
+	rootRange := traversal[0].SourceRange()
+
+	switch {
+	case root == "var" || root == "local" || contains(runtimeNamespaces, root):
+	case strings.HasPrefix(root, "NOMAD_"):
+		if isRuntimeEnvVariable(root) {
+			return nil
+		}
+
+		names := make([]string, 0, len(runtimeEnvVariables))
+		for _, v := range runtimeEnvVariables {
+			names = append(names, v.Name)
+		}
+
+		detail := fmt.Sprintf("%q is not an environment variable set by nomad.", root)
+		if suggestion := closestName(root, names); suggestion != "" {
+			detail += fmt.Sprintf(" Did you mean %q?", suggestion)
+		}
+
+		return hcl.Diagnostics{
+			{
+				Severity: hcl.DiagWarning,
+				Summary:  "Unknown runtime environment variable",
+				Detail:   detail,
+				Subject:  rootRange.Ptr(),
+				Extra:    rules.DiagnosticExtra("unknown-env-variable"),
+			},
+		}
+	default:
+		names := append([]string{"var", "local"}, runtimeNamespaces...)
+
+		detail := fmt.Sprintf("There is no variable namespace named %q, expected `var`, `local`, `attr`, `node`, `meta`, `env` or a `NOMAD_*` environment variable.", root)
+		if suggestion := closestName(root, names); suggestion != "" {
+			detail += fmt.Sprintf(" Did you mean %q?", suggestion)
+		}
+
+		return hcl.Diagnostics{
+			{
+				Severity: hcl.DiagError,
+				Summary:  "Unknown variable namespace",
+				Detail:   detail,
+				Subject:  rootRange.Ptr(),
+				Extra:    rules.DiagnosticExtra("unknown-namespace"),
+			},
+		}
+	}
+
+	if root != "node" {
+		return nil
+	}
+
+	path, r, ok := runtimePath(traversal)
+	if !ok {
+		return nil
+	}
+
+	names := make([]string, 0, len(runtimeNodeVariables))
+	for _, v := range runtimeNodeVariables {
+		if v.Name == path {
+			return nil
+		}
+		names = append(names, v.Name)
+	}
+
+	detail := fmt.Sprintf("The client node has no property named %q.", path)
+	if suggestion := closestName(path, names); suggestion != "" {
+		detail += fmt.Sprintf(" Did you mean %q?", suggestion)
+	}
+
+	return hcl.Diagnostics{
+		{
+			Severity: hcl.DiagWarning,
+			Summary:  "Unknown node property",
+			Detail:   detail,
+			Subject:  r.Ptr(),
+			Extra:    rules.DiagnosticExtra("unknown-node-property"),
+		},
+	}
+}
+
+// runtimePath returns the dotted path following the root of a traversal,
+// e.g. "unique.name" for `node.unique.name`, along with its range. Index
+// steps end the path.
+func runtimePath(traversal hcl.Traversal) (string, hcl.Range, bool) {
+	var names []string
+	var r hcl.Range
+
+	for _, step := range traversal[1:] {
+		attr, ok := step.(hcl.TraverseAttr)
+		if !ok {
+			break
+		}
+
+		if len(names) == 0 {
+			r = attr.SrcRange
+			if r.End.Byte-r.Start.Byte == len(attr.Name)+1 {
+				r.Start.Byte += 1
+				r.Start.Column += 1
+			}
+		}
+
+		names = append(names, attr.Name)
+		r.End = attr.SrcRange.End
+	}
+
+	if len(names) == 0 {
+		return "", hcl.Range{}, false
+	}
+
+	return strings.Join(names, "."), r, true
+}
+
+// CollectRuntimeCompletions completes the runtime variable, such as
+// `attr.kernel.na` or `NOMAD_AL`, ending at offset in src. Node metadata
+// keys are taken from the `meta.*` references in files and job metadata keys
+// from their `meta` blocks. The boolean result is false when there is no
+// runtime variable to complete at offset.
+func CollectRuntimeCompletions(src []byte, offset int, files map[string]*hcl.File) ([]protocol.CompletionItem, bool) {
+	prefix := runtimePrefix(src, offset)
+	if prefix == "" || !inExpression(src, offset) {
+		return nil, false
+	}
+
+	root, path, dotted := strings.Cut(prefix, ".")
+
+	var variables []runtimeVariable
+	replaced := prefix
+
+	switch {
+	case dotted && root == "node":
+		variables = runtimeNodeVariables
+		replaced = path
+	case dotted && root == "attr":
+		variables = runtimeAttributes
+		replaced = path
+	case dotted && root == "meta":
+		for _, key := range metaReferences(files) {
+			variables = append(variables, runtimeVariable{key, "Metadata of the client node."})
+		}
+		replaced = path
+	case !dotted && strings.HasPrefix(root, "NOMAD_"):
+		variables = append(variables, runtimeEnvVariables...)
+
+		for _, key := range metaKeys(files) {
+			variables = append(variables, runtimeVariable{"NOMAD_META_" + key, "The value of the job metadata key."})
+		}
+	default:
+		return nil, false
+	}
+
+	start := protocolPosition(src, offset-len(replaced))
+	end := protocolPosition(src, offset)
+
+	items := []protocol.CompletionItem{}
+
+	for _, v := range variables {
+		items = append(items, protocol.CompletionItem{
+			Label:  v.Name,
+			Kind:   protocol.CompletionItemKindVariable,
+			Detail: v.Description,
+			TextEdit: &protocol.TextEdit{
+				Range:   protocol.Range{Start: start, End: end},
+				NewText: v.Name,
+			},
+		})
+	}
+
+	if !dotted {
+		for _, v := range runtimeEnvPrefixes {
+			items = append(items, protocol.CompletionItem{
+				Label:            v.Name + "<label>",
+				Kind:             protocol.CompletionItemKindVariable,
+				Detail:           v.Description,
+				FilterText:       v.Name,
+				InsertTextFormat: protocol.InsertTextFormatSnippet,
+				TextEdit: &protocol.TextEdit{
+					Range:   protocol.Range{Start: start, End: end},
+					NewText: v.Name + "${1:label}",
+				},
+			})
+		}
+	}
+
+	return items, true
+}
+
+// runtimePrefix returns the possibly dotted reference, e.g.
+// `attr.unique.network.ip-`, written directly before offset in src.
+func runtimePrefix(src []byte, offset int) string {
+	if offset > len(src) {
+		offset = len(src)
+	}
+
+	start := offset
+	for start > 0 && (isIdentifierByte(src[start-1]) || src[start-1] == '.' || src[start-1] == '-') {
+		start--
+	}
+
+	return string(src[start:offset])
+}
+
+// metaReferences returns the sorted keys of the `meta.*` references in files.
+func metaReferences(files map[string]*hcl.File) []string {
+	seen := map[string]bool{}
+
+	for _, file := range files {
+		for _, traversal := range CollectTraversals(file.Body, "meta") {
+			if path, _, ok := runtimePath(traversal); ok {
+				seen[path] = true
+			}
+		}
+	}
+
+	return sortedKeys(seen)
+}
+
+// metaKeys returns the sorted keys set in the `meta` blocks of files.
+func metaKeys(files map[string]*hcl.File) []string {
+	seen := map[string]bool{}
+
+	for _, file := range files {
+		syntaxBody, ok := file.Body.(*hclsyntax.Body)
+		if !ok {
+			continue
+		}
+
+		hclsyntax.VisitAll(syntaxBody, func(node hclsyntax.Node) hcl.Diagnostics {
+			if b, ok := node.(*hclsyntax.Block); ok && b.Type == "meta" {
+				for name := range b.Body.Attributes {
+					seen[name] = true
+				}
+			}
+			return nil
+		})
+	}
+
+	return sortedKeys(seen)
+}
+
+// protocolPosition returns the protocol position of offset in src, counting
+// characters the same way CalculateByteOffset does.
+func protocolPosition(src []byte, offset int) protocol.Position {
+	if offset > len(src) {
+		offset = len(src)
+	}
+
+	var pos protocol.Position
+
+	for i := 0; i < offset; {
+		r, size := utf8.DecodeRune(src[i:])
+		if r == '\n' {
+			pos.Line++
+			pos.Character = 0
+		} else {
+			pos.Character++
+		}
+		i += size
+	}
+
+	return pos
+}
+
+// sortedSyntaxAttributes returns attrs ordered by their position in the file.
+func sortedSyntaxAttributes(attrs hclsyntax.Attributes) []*hclsyntax.Attribute {
+	sorted := make([]*hclsyntax.Attribute, 0, len(attrs))
+	for _, attr := range attrs {
+		sorted = append(sorted, attr)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].SrcRange.Start.Byte < sorted[j].SrcRange.Start.Byte
+	})
+
+	return sorted
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,230 @@
+package lsp
+
+import (
+	"sort"
+	"strings"
+
+	hclschema "github.com/hashicorp/hcl-lang/schema"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/loczek/nomad-ls/internal/schema"
+	"go.lsp.dev/protocol"
+)
+
+// The legend advertised to clients. Keywords and attributes known to the
+// schema are reported as `keyword` and `property` with the `defaultLibrary`
+// modifier, while unknown ones are reported as `type` and `parameter` so
+// that typos stand out before diagnostics arrive.
+var semanticTokenTypes = []protocol.SemanticTokenTypes{
+	protocol.SemanticTokenKeyword,
+	protocol.SemanticTokenType,
+	protocol.SemanticTokenClass,
+	protocol.SemanticTokenProperty,
+	protocol.SemanticTokenParameter,
+	protocol.SemanticTokenNamespace,
+	protocol.SemanticTokenVariable,
+	protocol.SemanticTokenMacro,
+	protocol.SemanticTokenFunction,
+}
+
+var semanticTokenModifiers = []protocol.SemanticTokenModifiers{
+	protocol.SemanticTokenModifierDeclaration,
+	protocol.SemanticTokenModifierDeprecated,
+	protocol.SemanticTokenModifierDefaultLibrary,
+}
+
+type SemanticToken struct {
+	Range     hcl.Range
+	Type      protocol.SemanticTokenTypes
+	Modifiers []protocol.SemanticTokenModifiers
+}
+
+// CollectSemanticTokens returns the tokens of body, sorted by position. The
+// schemas of driver dependent blocks are resolved in ctx, which may be nil.
+func CollectSemanticTokens(body hcl.Body, ctx *hcl.EvalContext) []SemanticToken {
+	var tokens []SemanticToken
+
+	syntaxBody, ok := body.(*hclsyntax.Body)
+	if !ok {
+		return tokens
+	}
+
+	CollectSemanticTokensDFS(syntaxBody, &tokens, &schema.RootBodySchema, ctx)
+
+	sort.SliceStable(tokens, func(i, j int) bool {
+		return tokens[i].Range.Start.Byte < tokens[j].Range.Start.Byte
+	})
+
+	return tokens
+}
+
+// CollectSemanticTokensDFS collects the tokens of body. A nil langSchema
+// means the schema of the body is not known, e.g. a driver `config` block
+// whose driver is not statically known, in which case names are neither
+// reported as known nor as unknown.
+func CollectSemanticTokensDFS(body *hclsyntax.Body, tokens *[]SemanticToken, langSchema *hclschema.BodySchema, ctx *hcl.EvalContext) {
+	for name, attr := range body.Attributes {
+		token := SemanticToken{Range: attr.NameRange, Type: protocol.SemanticTokenProperty}
+
+		if langSchema != nil && langSchema.AnyAttribute == nil {
+			if attrSchema, ok := langSchema.Attributes[name]; ok {
+				token.Modifiers = append(token.Modifiers, protocol.SemanticTokenModifierDefaultLibrary)
+				if attrSchema.IsDeprecated {
+					token.Modifiers = append(token.Modifiers, protocol.SemanticTokenModifierDeprecated)
+				}
+			} else {
+				token.Type = protocol.SemanticTokenParameter
+			}
+		}
+
+		*tokens = append(*tokens, token)
+
+		collectExpressionSemanticTokens(attr.Expr, tokens)
+	}
+
+	var bodyContent *hcl.BodyContent
+	if langSchema != nil {
+		bodyContent, _, _ = body.PartialContent(langSchema.ToHCLSchema())
+	}
+
+	for _, b := range body.Blocks {
+		token := SemanticToken{Range: b.TypeRange, Type: protocol.SemanticTokenKeyword}
+
+		var blockSchema *hclschema.BlockSchema
+		var childSchema *hclschema.BodySchema
+
+		if langSchema != nil {
+			blockSchema = langSchema.Blocks[b.Type]
+
+			if blockSchema == nil {
+				token.Type = protocol.SemanticTokenType
+			} else {
+				token.Modifiers = append(token.Modifiers, protocol.SemanticTokenModifierDefaultLibrary)
+				if blockSchema.IsDeprecated {
+					token.Modifiers = append(token.Modifiers, protocol.SemanticTokenModifierDeprecated)
+				}
+
+				if blockSchema.Body != nil {
+					childSchema = blockSchema.Body
+				} else if blockSchema.DependentBody != nil {
+					childSchema = dependentBodySchema(blockSchema, bodyContent, ctx)
+				}
+			}
+		}
+
+		*tokens = append(*tokens, token)
+
+		for _, r := range b.LabelRanges {
+			if b.Type == "variable" {
+				*tokens = append(*tokens, SemanticToken{
+					Range:     r,
+					Type:      protocol.SemanticTokenVariable,
+					Modifiers: []protocol.SemanticTokenModifiers{protocol.SemanticTokenModifierDeclaration},
+				})
+			} else {
+				*tokens = append(*tokens, SemanticToken{Range: r, Type: protocol.SemanticTokenClass})
+			}
+		}
+
+		CollectSemanticTokensDFS(b.Body, tokens, childSchema, ctx)
+	}
+}
+
+func collectExpressionSemanticTokens(expr hclsyntax.Expression, tokens *[]SemanticToken) {
+	hclsyntax.VisitAll(expr, func(node hclsyntax.Node) hcl.Diagnostics {
+		switch n := node.(type) {
+		case *hclsyntax.FunctionCallExpr:
+			*tokens = append(*tokens, SemanticToken{Range: n.NameRange, Type: protocol.SemanticTokenFunction})
+		case *hclsyntax.ScopeTraversalExpr:
+			root := n.Traversal.RootName()
+
+			switch {
+			case root == "var" || root == "local":
+				*tokens = append(*tokens, SemanticToken{Range: n.Traversal[0].SourceRange(), Type: protocol.SemanticTokenNamespace})
+				if _, r, ok := traversalName(n.Traversal); ok {
+					*tokens = append(*tokens, SemanticToken{Range: r, Type: protocol.SemanticTokenVariable})
+				}
+			case isRuntimeVariable(root):
+				*tokens = append(*tokens, SemanticToken{
+					Range:     n.SrcRange,
+					Type:      protocol.SemanticTokenMacro,
+					Modifiers: []protocol.SemanticTokenModifiers{protocol.SemanticTokenModifierDefaultLibrary},
+				})
+			default:
+				*tokens = append(*tokens, SemanticToken{Range: n.SrcRange, Type: protocol.SemanticTokenVariable})
+			}
+		}
+		return nil
+	})
+}
+
+// isRuntimeVariable reports whether root is the root of a nomad runtime
+// interpolation such as `${attr.kernel.name}` or `${NOMAD_PORT_http}`.
+func isRuntimeVariable(root string) bool {
+	switch root {
+	case "attr", "node", "meta", "env":
+		return true
+	}
+
+	return strings.HasPrefix(root, "NOMAD_")
+}
+
+// EncodeSemanticTokens encodes tokens, which must be sorted by position, in
+// the relative format of the protocol. Only tokens overlapping r are encoded
+// when r is not nil.
+func EncodeSemanticTokens(tokens []SemanticToken, r *protocol.Range) []uint32 {
+	data := []uint32{}
+
+	var prevLine, prevChar uint32
+
+	for _, token := range tokens {
+		tokenRange := protocolRange(token.Range)
+
+		// multi-line tokens are not supported by every client
+		if tokenRange.Start.Line != tokenRange.End.Line {
+			continue
+		}
+
+		if r != nil && (tokenRange.End.Line < r.Start.Line || tokenRange.Start.Line > r.End.Line) {
+			continue
+		}
+
+		deltaLine := tokenRange.Start.Line - prevLine
+		deltaChar := tokenRange.Start.Character
+		if deltaLine == 0 {
+			deltaChar -= prevChar
+		}
+
+		var modifiers uint32
+		for _, m := range token.Modifiers {
+			for i, legendModifier := range semanticTokenModifiers {
+				if m == legendModifier {
+					modifiers |= 1 << i
+				}
+			}
+		}
+
+		data = append(data,
+			deltaLine,
+			deltaChar,
+			tokenRange.End.Character-tokenRange.Start.Character,
+			uint32(semanticTokenTypeIndex(token.Type)),
+			modifiers,
+		)
+
+		prevLine = tokenRange.Start.Line
+		prevChar = tokenRange.Start.Character
+	}
+
+	return data
+}
+
+func semanticTokenTypeIndex(tokenType protocol.SemanticTokenTypes) int {
+	for i, t := range semanticTokenTypes {
+		if t == tokenType {
+			return i
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,219 @@
+package lsp
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/loczek/nomad-ls/internal/rules"
+)
+
+const (
+	// ignoreDirective suppresses the diagnostics of the listed rules in the
+	// block or attribute on the line following the comment, e.g.
+	// `# nomad-ls:ignore unused-variable`.
+	ignoreDirective = "nomad-ls:ignore"
+
+	// ignoreFileDirective suppresses the diagnostics of the listed rules in
+	// the whole file when it appears in the comments at the top of the file.
+	ignoreFileDirective = "nomad-ls:ignore-file"
+)
+
+// suppression is a comment suppressing the diagnostics of some rules within
+// a range of the file.
+type suppression struct {
+	Rules []string
+
+	// Comment is the range of the comment itself.
+	Comment hcl.Range
+
+	// Range is the range the diagnostics are suppressed in, nil for the
+	// whole file.
+	Range *hcl.Range
+}
+
+// collectSuppressions returns the suppression comments of file.
+func collectSuppressions(file *hcl.File) []suppression {
+	var suppressions []suppression
+
+	filename := file.Body.MissingItemRange().Filename
+	tokens, _ := hclsyntax.LexConfig(file.Bytes, filename, hcl.InitialPos)
+
+	leading := true
+
+	for _, token := range tokens {
+		if token.Type != hclsyntax.TokenComment {
+			if token.Type != hclsyntax.TokenNewline {
+				leading = false
+			}
+			continue
+		}
+
+		text := string(token.Bytes)
+
+		fields := strings.Fields(strings.ReplaceAll(commentText(text), ",", " "))
+		if len(fields) < 2 {
+			continue
+		}
+
+		// `#` and `//` comments include the newline ending them
+		r := token.Range
+		if trimmed := strings.TrimRight(text, "\r\n"); len(trimmed) < len(text) {
+			r.End = hcl.Pos{
+				Line:   r.Start.Line,
+				Column: r.Start.Column + len(trimmed),
+				Byte:   r.Start.Byte + len(trimmed),
+			}
+		}
+
+		switch fields[0] {
+		case ignoreFileDirective:
+			if leading {
+				suppressions = append(suppressions, suppression{Rules: fields[1:], Comment: r})
+			}
+		case ignoreDirective:
+			suppressed := suppressedRange(file, r.End.Line+1)
+			suppressions = append(suppressions, suppression{Rules: fields[1:], Comment: r, Range: &suppressed})
+		}
+	}
+
+	return suppressions
+}
+
+// commentText returns the text of a comment without its delimiters.
+func commentText(comment string) string {
+	switch {
+	case strings.HasPrefix(comment, "#"):
+		comment = comment[1:]
+	case strings.HasPrefix(comment, "//"):
+		comment = comment[2:]
+	case strings.HasPrefix(comment, "/*"):
+		comment = strings.TrimSuffix(comment[2:], "*/")
+	}
+
+	return strings.TrimSpace(comment)
+}
+
+// suppressedRange returns the range of the block or attribute of file
+// starting on line, or the range of the line itself when none does.
+func suppressedRange(file *hcl.File, line int) hcl.Range {
+	if body, ok := file.Body.(*hclsyntax.Body); ok {
+		if r, ok := itemRange(body, line); ok {
+			return r
+		}
+	}
+
+	start := 0
+	for l := 1; l < line; l++ {
+		i := bytes.IndexByte(file.Bytes[start:], '\n')
+		if i < 0 {
+			return hcl.Range{}
+		}
+		start += i + 1
+	}
+
+	end := len(file.Bytes)
+	if i := bytes.IndexByte(file.Bytes[start:], '\n'); i >= 0 {
+		end = start + i
+	}
+
+	return hcl.Range{
+		Filename: file.Body.MissingItemRange().Filename,
+		Start:    hcl.Pos{Line: line, Column: 1, Byte: start},
+		End:      hcl.Pos{Line: line, Column: end - start + 1, Byte: end},
+	}
+}
+
+// itemRange returns the range of the block or attribute of body, or of one
+// of its nested bodies, starting on line.
+func itemRange(body *hclsyntax.Body, line int) (hcl.Range, bool) {
+	for _, attr := range body.Attributes {
+		if attr.SrcRange.Start.Line == line {
+			return attr.SrcRange, true
+		}
+	}
+
+	for _, b := range body.Blocks {
+		r := b.Range()
+
+		if r.Start.Line == line {
+			return r, true
+		}
+
+		if r.Start.Line < line && line <= r.End.Line {
+			return itemRange(b.Body, line)
+		}
+	}
+
+	return hcl.Range{}, false
+}
+
+// suppresses reports whether s suppresses diag.
+func (s suppression) suppresses(diag *hcl.Diagnostic) bool {
+	id := rules.ID(diag)
+	if id == "" || !contains(s.Rules, id) {
+		return false
+	}
+
+	if s.Range == nil {
+		return true
+	}
+
+	return diag.Subject != nil && s.Range.ContainsOffset(diag.Subject.Start.Byte)
+}
+
+// FilterSuppressedDiagnostics removes the diagnostics of diags suppressed by
+// the comments of file, and reports the rules of those comments which do not
+// suppress any diagnostic as hints. Rules disabled by config report nothing to
+// suppress, so they are not reported.
+func FilterSuppressedDiagnostics(file *hcl.File, diags hcl.Diagnostics, config *rules.Config) hcl.Diagnostics {
+	suppressions := collectSuppressions(file)
+	if len(suppressions) == 0 {
+		return diags
+	}
+
+	// used holds the rules which suppressed a diagnostic, by suppression
+	used := make([]map[string]bool, len(suppressions))
+
+	var filtered hcl.Diagnostics
+
+	for _, diag := range diags {
+		suppressed := false
+
+		for i, s := range suppressions {
+			if !s.suppresses(diag) {
+				continue
+			}
+
+			if used[i] == nil {
+				used[i] = map[string]bool{}
+			}
+			used[i][rules.ID(diag)] = true
+			suppressed = true
+		}
+
+		if !suppressed {
+			filtered = append(filtered, diag)
+		}
+	}
+
+	for i, s := range suppressions {
+		for _, id := range s.Rules {
+			if used[i][id] || !config.Enabled(id) {
+				continue
+			}
+
+			filtered = filtered.Append(&hcl.Diagnostic{
+				Severity: DiagHint,
+				Summary:  "Unused suppression",
+				Detail:   fmt.Sprintf("No diagnostic of the rule %q is suppressed by this comment.", id),
+				Subject:  s.Comment.Ptr(),
+				Extra:    rules.DiagnosticExtra("unused-suppression"),
+			})
+		}
+	}
+
+	return filtered
+}
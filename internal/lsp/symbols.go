@@ -0,0 +1,157 @@
+package lsp
+
+import (
+	"strings"
+
+	hclschema "github.com/hashicorp/hcl-lang/schema"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/loczek/nomad-ls/internal/schema"
+	"github.com/zclconf/go-cty/cty"
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+var symbolKinds = map[string]protocol.SymbolKind{
+	"variable":  protocol.SymbolKindVariable,
+	"variables": protocol.SymbolKindNamespace,
+	"job":       protocol.SymbolKindModule,
+	"group":     protocol.SymbolKindNamespace,
+	"task":      protocol.SymbolKindClass,
+	"service":   protocol.SymbolKindInterface,
+	"template":  protocol.SymbolKindFile,
+	"volume":    protocol.SymbolKindPackage,
+}
+
+// symbolNameAttributes maps unlabeled block types to the attribute that best
+// identifies them in an outline, e.g. the `name` of a service.
+var symbolNameAttributes = map[string]string{
+	"service":  "name",
+	"check":    "name",
+	"template": "destination",
+}
+
+// CollectDocumentSymbols returns the outline of body. The schemas of driver
+// dependent blocks are resolved in ctx, which may be nil.
+func CollectDocumentSymbols(body hcl.Body, ctx *hcl.EvalContext) []protocol.DocumentSymbol {
+	return CollectDocumentSymbolsDFS(body, &schema.RootBodySchema, ctx)
+}
+
+func CollectDocumentSymbolsDFS(body hcl.Body, langSchema *hclschema.BodySchema, ctx *hcl.EvalContext) []protocol.DocumentSymbol {
+	symbols := []protocol.DocumentSymbol{}
+
+	if langSchema == nil {
+		return symbols
+	}
+
+	var bodyContent *hcl.BodyContent
+
+	if langSchema.AnyAttribute != nil {
+		bodyContent, _, _ = body.PartialContent(langSchema.ToHCLSchema())
+	} else {
+		bodyContent, _ = body.Content(langSchema.ToHCLSchema())
+	}
+
+	for _, b := range bodyContent.Blocks {
+		var children []protocol.DocumentSymbol
+
+		if langSchema.Blocks[b.Type] != nil && langSchema.Blocks[b.Type].Body != nil {
+			children = CollectDocumentSymbolsDFS(b.Body, langSchema.Blocks[b.Type].Body, ctx)
+		} else if langSchema.Blocks[b.Type] != nil && langSchema.Blocks[b.Type].DependentBody != nil {
+			children = CollectDocumentSymbolsDFS(b.Body, dependentBodySchema(langSchema.Blocks[b.Type], bodyContent, ctx), ctx)
+		}
+
+		kind, ok := symbolKinds[b.Type]
+		if !ok {
+			kind = protocol.SymbolKindStruct
+		}
+
+		selectionRange := b.TypeRange
+		if len(b.LabelRanges) > 0 {
+			selectionRange = b.LabelRanges[0]
+		}
+
+		r := b.DefRange
+		if syntaxBody, ok := b.Body.(*hclsyntax.Body); ok {
+			r = hcl.RangeBetween(b.TypeRange, syntaxBody.SrcRange)
+		}
+
+		symbols = append(symbols, protocol.DocumentSymbol{
+			Name:           symbolName(b),
+			Detail:         b.Type,
+			Kind:           kind,
+			Range:          protocolRange(r),
+			SelectionRange: protocolRange(selectionRange),
+			Children:       children,
+		})
+	}
+
+	return symbols
+}
+
+// CollectWorkspaceSymbols returns the symbols of every file whose name
+// contains query, ignoring case. Container names are the dotted path of the
+// enclosing symbols, e.g. `billing.workers` for a task in group `workers`.
+// Files are outlined without an eval context, which only driver dependent
+// `config` blocks need.
+func CollectWorkspaceSymbols(files map[string]*hcl.File, query string) []protocol.SymbolInformation {
+	symbols := []protocol.SymbolInformation{}
+
+	for _, filename := range sortedFilenames(files) {
+		collectWorkspaceSymbolsDFS(&symbols, filename, CollectDocumentSymbols(files[filename].Body, nil), "", strings.ToLower(query))
+	}
+
+	return symbols
+}
+
+func collectWorkspaceSymbolsDFS(
+	symbols *[]protocol.SymbolInformation,
+	filename string,
+	documentSymbols []protocol.DocumentSymbol,
+	container string,
+	query string,
+) {
+	for _, s := range documentSymbols {
+		if strings.Contains(strings.ToLower(s.Name), query) {
+			*symbols = append(*symbols, protocol.SymbolInformation{
+				Name: s.Name,
+				Kind: s.Kind,
+				Location: protocol.Location{
+					URI:   uri.File(filename),
+					Range: s.SelectionRange,
+				},
+				ContainerName: container,
+			})
+		}
+
+		childContainer := s.Name
+		if container != "" {
+			childContainer = container + "." + s.Name
+		}
+
+		collectWorkspaceSymbolsDFS(symbols, filename, s.Children, childContainer, query)
+	}
+}
+
+func symbolName(b *hcl.Block) string {
+	if len(b.Labels) > 0 && b.Labels[0] != "" {
+		return b.Labels[0]
+	}
+
+	attrName, ok := symbolNameAttributes[b.Type]
+	if !ok {
+		return b.Type
+	}
+
+	attrs, _ := b.Body.JustAttributes()
+	if attrs[attrName] == nil {
+		return b.Type
+	}
+
+	val, diags := attrs[attrName].Expr.Value(&hcl.EvalContext{})
+	if diags.HasErrors() || !val.IsKnown() || val.IsNull() || val.Type() != cty.String || val.AsString() == "" {
+		return b.Type
+	}
+
+	return val.AsString()
+}
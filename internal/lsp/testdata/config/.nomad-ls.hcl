@@ -0,0 +1,13 @@
+rule "canary-promotion" {
+  enabled = false
+}
+
+rule "scaling-count" {
+  severity = "warning"
+}
+
+policy {
+  required_meta       = ["team", "owner"]
+  allowed_datacenters = ["eu-west-*"]
+  max_memory          = 2048
+}
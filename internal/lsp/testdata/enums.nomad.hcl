@@ -0,0 +1,31 @@
+job "enums" {
+  type = "servce"
+
+  group "web" {
+    network {
+      mode = "cni/mynet"
+    }
+
+    restart {
+      mode = "fail"
+    }
+
+    constraint {
+      attribute = "${attr.kernel.name}"
+      operator  = "=="
+      value     = "linux"
+    }
+
+    task "web" {
+      driver = "docker"
+
+      config {
+        image = "nginx"
+      }
+
+      lifecycle {
+        hook = "prestat"
+      }
+    }
+  }
+}
@@ -0,0 +1,29 @@
+job "formats" {
+  type = "batch"
+
+  periodic {
+    crons     = ["@daily", "*/15 * * * MON-FRI", "0 25 * * *", "@dayly"]
+    time_zone = "Mars/Olympus"
+  }
+
+  group "report" {
+    ephemeral_disk {
+      size = 1.5
+    }
+
+    reschedule {
+      interval = "1h"
+      delay    = 30
+    }
+
+    task "report" {
+      driver       = "docker"
+      kill_timeout = "5 seconds"
+
+      config {
+        image              = "busybox"
+        image_pull_timeout = "5x"
+      }
+    }
+  }
+}
@@ -0,0 +1,7 @@
+locals {
+  args    = join(" ", ["--port", "8080"])
+  upper   = uper("web")
+  missing = format()
+  extra   = lower("A", "B")
+  spread  = max([1, 2]...)
+}
@@ -0,0 +1,28 @@
+variable "tag" {
+  type    = string
+  default = "1.25"
+}
+
+locals {
+  image = "nginx:${var.tag}"
+  name  = local.prefix
+  a     = local.b
+  b     = local.a
+}
+
+job "web" {
+  group "web" {
+    task "web" {
+      driver = "docker"
+
+      config {
+        image = local.image
+      }
+
+      meta {
+        name  = local.name
+        cycle = local.a
+      }
+    }
+  }
+}
@@ -0,0 +1,19 @@
+job "example" {
+  group "app" {
+    task "server" {
+      user = "nobody"
+    }
+
+    task "web" {
+      driver = "docker"
+
+      config {}
+
+      lifecycle {}
+
+      resources {
+        numa {}
+      }
+    }
+  }
+}
@@ -0,0 +1,49 @@
+variable "private_key" {
+  type = string
+}
+
+variable "secret" {
+  type = string
+}
+
+locals {
+  subnet = cidrsubnet("10.0.0.0/16", 8, 1)
+}
+
+job "functions" {
+  datacenters = ["dc1"]
+
+  meta {
+    count    = tostring(tonumber("3"))
+    enabled  = tostring(tobool("true"))
+    tags     = join(",", tolist(toset(["a", "b"])))
+    owner    = lookup(tomap({ owner = "ops" }), "owner", "")
+    md5      = md5("nomad")
+    sha1     = sha1("nomad")
+    sha256   = sha256("nomad")
+    sha512   = sha512("nomad")
+    password = bcrypt(var.secret)
+    token    = rsadecrypt(var.secret, var.private_key)
+    id       = uuidv4()
+    name_id  = uuidv5("dns", "nomad.example.com")
+    config   = yamlencode(yamldecode("port: 8080"))
+    abs      = abspath(".")
+    base     = basename("/etc/nomad.d/nomad.hcl")
+    dir      = dirname("/etc/nomad.d/nomad.hcl")
+    home     = pathexpand("~/nomad")
+    files    = join(",", fileset(".", "*.tpl"))
+    host     = cidrhost(local.subnet, 5)
+    netmask  = cidrnetmask(local.subnet)
+    subnets  = join(",", cidrsubnets("10.1.0.0/16", 4, 4))
+  }
+
+  group "app" {
+    task "app" {
+      driver = "docker"
+
+      config {
+        image = "redis"
+      }
+    }
+  }
+}
@@ -0,0 +1,31 @@
+variable "driver" {
+  type    = string
+  default = "docker"
+}
+
+variable "image" {
+  type = string
+}
+
+variable "instances" {
+  default = "three"
+}
+
+locals {
+  name = upper(var.driver)
+}
+
+job "web" {
+  group "web" {
+    count = var.instances
+
+    task "web" {
+      driver = var.driver
+
+      config {
+        image   = var.image
+        comand  = "nginx"
+      }
+    }
+  }
+}
@@ -0,0 +1,28 @@
+job "api" {
+  datacenters = ["eu-west-1", "us-east-1"]
+
+  meta {
+    team = "platform"
+  }
+
+  update {
+    canary = 1
+  }
+
+  group "api" {
+    count = 4
+
+    scaling {
+      min = 1
+      max = 3
+    }
+
+    task "server" {
+      driver = "docker"
+
+      resources {
+        memory = 4096
+      }
+    }
+  }
+}
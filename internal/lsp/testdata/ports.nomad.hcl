@@ -0,0 +1,44 @@
+job "ports" {
+  group "web" {
+    network {
+      port "http" {
+        to = 8080
+      }
+
+      port "metrics" {}
+
+      port "admin" {}
+
+      port "debug" {}
+    }
+
+    service {
+      name = "web"
+      port = "http"
+
+      check {
+        type = "http"
+        port = "htp"
+      }
+    }
+
+    task "web" {
+      driver = "docker"
+
+      config {
+        image = "nginx"
+        ports = ["http", "9090"]
+      }
+
+      env {
+        METRICS = "${NOMAD_PORT_metrics}"
+        GRPC    = "${NOMAD_HOST_ADDR_grpc}"
+      }
+
+      template {
+        data        = "admin={{ env \"NOMAD_ADDR_admin\" }}"
+        destination = "local/env"
+      }
+    }
+  }
+}
@@ -0,0 +1,5 @@
+# nomad-ls:job variables.nomad.hcl
+
+datacenters = "dc1"
+count       = 3
+regon       = "global"
@@ -0,0 +1,70 @@
+job "web" {
+  datacenters = ["dc1"]
+
+  periodic {
+    crons = ["@daily"]
+  }
+
+  update {
+    canary           = 2
+    min_healthy_time = "10m"
+  }
+
+  group "frontend" {
+    count = 5
+
+    scaling {
+      min = 1
+      max = 3
+    }
+
+    update {
+      healthy_deadline  = "5m"
+      progress_deadline = "4m"
+    }
+  }
+
+  group "backend" {
+    count = 2
+
+    update {
+      auto_promote = true
+    }
+
+    scaling {
+      min = 3
+      max = 1
+    }
+  }
+}
+
+job "report" {
+  type = "batch"
+
+  parameterized {
+    payload = "optional"
+  }
+
+  group "report" {
+    update {
+      max_parallel = 1
+    }
+  }
+}
+
+job "cleanup" {
+  type = "sysbatch"
+
+  periodic {
+    crons = ["@hourly"]
+  }
+
+  group "cleanup" {
+    count = 0
+
+    scaling {
+      min = 1
+      max = 2
+    }
+  }
+}
@@ -0,0 +1,34 @@
+job "runtime" {
+  meta {
+    team = "platform"
+  }
+
+  group "web" {
+    constraint {
+      attribute = "${attr.kernel.name}"
+      value     = "linux"
+    }
+
+    constraint {
+      attribute = "${nod.datacenter}"
+      value     = "${node.datacentre}"
+    }
+
+    task "web" {
+      driver = "docker"
+
+      config {
+        image = "nginx"
+        args  = [for arg in ["-p", "${NOMAD_PORT_http}"] : arg]
+      }
+
+      env {
+        ALLOC  = "${NOMAD_ALLOC_ID}"
+        TEAM   = "${NOMAD_META_team}"
+        DIR    = "${NOMAD_TASKDIR}/config"
+        RACK   = "${meta.rack}"
+        TAGS   = join(",", convert(var.tags, list(string)))
+      }
+    }
+  }
+}
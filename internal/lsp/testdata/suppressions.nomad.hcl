@@ -0,0 +1,33 @@
+# nomad-ls:ignore-file duplicate-variable
+
+# nomad-ls:ignore unused-variable
+variable "legacy" {
+  default = "nginx"
+}
+
+variable "unused" {}
+
+variable "image" {}
+
+job "app" {
+  # nomad-ls:ignore unused-variable, undeclared-variable
+  datacenters = [var.region]
+
+  # nomad-ls:ignore canary-promotion
+  update {
+    canary = 1
+  }
+
+  group "app" {
+    task "app" {
+      driver = "docker"
+
+      # nomad-ls:ignore unknown-function
+      user = whoami()
+
+      config {
+        image = var.image
+      }
+    }
+  }
+}
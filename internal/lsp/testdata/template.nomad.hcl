@@ -0,0 +1,26 @@
+job "web" {
+  group "web" {
+    task "nginx" {
+      driver = "docker"
+
+      config {
+        image = "nginx:latest"
+        args = [
+          "-g",
+          "daemon off;",
+        ]
+      }
+
+      template {
+        data        = <<EOF
+upstream backend {
+{{ range service "api" }}
+  server {{ .Address }}:{{ .Port }};
+{{ end }}
+}
+EOF
+        destination = "local/nginx.conf"
+      }
+    }
+  }
+}
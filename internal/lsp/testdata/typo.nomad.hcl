@@ -0,0 +1,14 @@
+job "example" {
+  update {
+    healthcheck = "checks"
+  }
+
+  grup "app" {}
+
+  group "app" {
+    task "server" {
+      driver      = "exec"
+      kill_timout = "10s"
+    }
+  }
+}
@@ -0,0 +1,26 @@
+variable "image" {
+  type    = string
+  default = "nginx"
+}
+
+variable "unused" {
+  type    = string
+  default = ""
+}
+
+variables {
+  image = "redis"
+}
+
+job "web" {
+  group "web" {
+    task "web" {
+      driver = "docker"
+
+      config {
+        image = var.image
+        args  = ["--port", var.port]
+      }
+    }
+  }
+}
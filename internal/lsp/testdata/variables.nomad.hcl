@@ -0,0 +1,57 @@
+variable "datacenters" {
+  type        = list(string)
+  default     = ["dc1"]
+  description = "The datacenters the job may be placed in."
+}
+
+variable "resources" {
+  type = object({
+    cpu    = number
+    memory = optional(number, 256)
+  })
+  default = {
+    cpu = 500
+  }
+}
+
+variable "count" {
+  type      = number
+  default   = "many"
+  sensitive = true
+
+  validation {
+    condition     = var.count > 0
+    error_message = "The count must be positive."
+  }
+}
+
+variable "region" {
+  type    = strin
+  default = "global"
+
+  validation {
+    condition     = true
+    error_message = "Always valid."
+  }
+}
+
+job "web" {
+  datacenters = var.datacenters
+
+  group "web" {
+    count = var.count
+
+    task "web" {
+      driver = "docker"
+
+      config {
+        image = "nginx"
+      }
+
+      resources {
+        cpu    = var.resources.cpu
+        memory = var.resources.memory
+      }
+    }
+  }
+}
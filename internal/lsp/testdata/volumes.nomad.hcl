@@ -0,0 +1,40 @@
+job "volumes" {
+  group "db" {
+    volume "data" {
+      type        = "csi"
+      source      = "db-data"
+      read_only   = true
+      access_mode = "single-node-writer"
+    }
+
+    volume "certs" {
+      type      = "host"
+      source    = "certs"
+      read_only = true
+    }
+
+    task "db" {
+      driver = "docker"
+
+      config {
+        image = "postgres"
+      }
+
+      volume_mount {
+        volume      = "data"
+        destination = "/var/lib/postgresql"
+      }
+
+      volume_mount {
+        volume      = "certs"
+        destination = "/certs"
+        read_only   = false
+      }
+
+      volume_mount {
+        volume      = "cert"
+        destination = "/backup"
+      }
+    }
+  }
+}
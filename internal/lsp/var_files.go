@@ -0,0 +1,176 @@
+package lsp
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/loczek/nomad-ls/internal/rules"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	"go.lsp.dev/protocol"
+)
+
+// collectVariablesByName returns the variables declared in files by name.
+// When a variable is declared more than once the first declaration wins.
+func collectVariablesByName(files map[string]*hcl.File) map[string]Variable {
+	variables := map[string]Variable{}
+
+	for _, filename := range sortedFilenames(files) {
+		for _, v := range CollectVariables(files[filename].Body) {
+			if _, ok := variables[v.Name]; !ok {
+				variables[v.Name] = v
+			}
+		}
+	}
+
+	return variables
+}
+
+// CollectVarFileDiagnostics validates a var-file against the variables
+// declared in jobs. Every attribute must set a declared variable to a static
+// value compatible with the type of the variable. Undeclared variables are
+// only reported when jobs declare at least one variable, since a var-file
+// without any job in reach cannot be checked.
+func CollectVarFileDiagnostics(body hcl.Body, jobs map[string]*hcl.File) hcl.Diagnostics {
+	attrs, diags := body.JustAttributes()
+
+	variables := collectVariablesByName(jobs)
+
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+
+	for _, attr := range sortedAttributes(attrs) {
+		val, valDiags := attr.Expr.Value(nil)
+		diags = diags.Extend(valDiags)
+
+		v, ok := variables[attr.Name]
+		if !ok {
+			if len(variables) == 0 {
+				continue
+			}
+
+			detail := fmt.Sprintf("A \"variable\" block with the name %q was not found.", attr.Name)
+			if suggestion := closestName(attr.Name, names); suggestion != "" {
+				detail += fmt.Sprintf(" Did you mean %q?", suggestion)
+			}
+
+			diags = diags.Append(&hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Undefined variable",
+				Detail:   detail,
+				Subject:  attr.NameRange.Ptr(),
+				Extra:    rules.DiagnosticExtra("undefined-var-file-variable"),
+			})
+
+			continue
+		}
+
+		if valDiags.HasErrors() {
+			continue
+		}
+
+		ty, defaults, typeDiags := v.ConstraintType()
+		if typeDiags.HasErrors() {
+			continue
+		}
+
+		if defaults != nil {
+			val = defaults.Apply(val)
+		}
+
+		if _, err := convert.Convert(val, ty); err != nil {
+			diags = diags.Append(&hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid value for variable",
+				Detail:   fmt.Sprintf("The value for variable %q is not compatible with its type constraint: %s.", attr.Name, err),
+				Subject:  attr.Expr.Range().Ptr(),
+				Extra:    rules.DiagnosticExtra("var-file-value"),
+			})
+		}
+	}
+
+	return diags
+}
+
+// CollectVarFileCompletions returns the variables declared in jobs that are
+// not set yet in the var-file body, to be inserted as `name = value`.
+func CollectVarFileCompletions(body hcl.Body, jobs map[string]*hcl.File) []protocol.CompletionItem {
+	items := []protocol.CompletionItem{}
+
+	set := map[string]bool{}
+	if syntaxBody, ok := body.(*hclsyntax.Body); ok {
+		for name := range syntaxBody.Attributes {
+			set[name] = true
+		}
+	}
+
+	variables := collectVariablesByName(jobs)
+
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if set[name] {
+			continue
+		}
+
+		v := variables[name]
+
+		ty, _, diags := v.ConstraintType()
+		if diags.HasErrors() {
+			ty = cty.DynamicPseudoType
+		}
+
+		insertText := fmt.Sprintf("%s = $0", name)
+		switch {
+		case ty == cty.String:
+			insertText = fmt.Sprintf("%s = \"$0\"", name)
+		case ty.IsListType() || ty.IsSetType() || ty.IsTupleType():
+			insertText = fmt.Sprintf("%s = [$0]", name)
+		case ty.IsMapType() || ty.IsObjectType():
+			insertText = fmt.Sprintf("%s = {$0}", name)
+		}
+
+		item := protocol.CompletionItem{
+			Label:            name,
+			Kind:             protocol.CompletionItemKindVariable,
+			Detail:           friendlyTypeName(ty),
+			InsertText:       insertText,
+			InsertTextFormat: protocol.InsertTextFormatSnippet,
+		}
+
+		if v.Description != nil {
+			if val, diags := v.Description.Value(nil); !diags.HasErrors() && val.Type() == cty.String && val.IsKnown() && !val.IsNull() {
+				item.Documentation = protocol.MarkupContent{
+					Kind:  protocol.Markdown,
+					Value: val.AsString(),
+				}
+			}
+		}
+
+		items = append(items, item)
+	}
+
+	return items
+}
+
+// sortedAttributes returns attrs ordered by their position in the file.
+func sortedAttributes(attrs hcl.Attributes) []*hcl.Attribute {
+	sorted := make([]*hcl.Attribute, 0, len(attrs))
+	for _, attr := range attrs {
+		sorted = append(sorted, attr)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Range.Start.Byte < sorted[j].Range.Start.Byte
+	})
+
+	return sorted
+}
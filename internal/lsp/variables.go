@@ -0,0 +1,327 @@
+package lsp
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/loczek/nomad-ls/internal/rules"
+	"github.com/loczek/nomad-ls/internal/schema"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// Variable is an input variable declared either with a `variable` block or
+// as an attribute of a `variables` block.
+type Variable struct {
+	Name      string
+	NameRange hcl.Range
+	DefRange  hcl.Range
+
+	Type        hcl.Expression
+	Default     hcl.Expression
+	Description hcl.Expression
+}
+
+// CollectVariables returns the input variables declared at the root of body,
+// ordered by their position in the file.
+func CollectVariables(body hcl.Body) []Variable {
+	var variables []Variable
+
+	bodyContent, _, _ := body.PartialContent(schema.RootBodySchema.ToHCLSchema())
+
+	for _, b := range bodyContent.Blocks {
+		switch b.Type {
+		case "variable":
+			if len(b.Labels) == 0 {
+				continue
+			}
+
+			variable := Variable{
+				Name:      b.Labels[0],
+				NameRange: labelNameRange(b.Labels[0], b.LabelRanges[0]),
+				DefRange:  b.DefRange,
+			}
+
+			variableContent, _, _ := b.Body.PartialContent(schema.VariableSchema.ToHCLSchema())
+
+			if attr := variableContent.Attributes["type"]; attr != nil {
+				variable.Type = attr.Expr
+			}
+			if attr := variableContent.Attributes["default"]; attr != nil {
+				variable.Default = attr.Expr
+			}
+			if attr := variableContent.Attributes["description"]; attr != nil {
+				variable.Description = attr.Expr
+			}
+
+			variables = append(variables, variable)
+		case "variables":
+			attrs, _ := b.Body.JustAttributes()
+
+			for name, attr := range attrs {
+				variables = append(variables, Variable{
+					Name:      name,
+					NameRange: attr.NameRange,
+					DefRange:  attr.Range,
+					Default:   attr.Expr,
+				})
+			}
+		}
+	}
+
+	sort.Slice(variables, func(i, j int) bool {
+		return variables[i].DefRange.Start.Byte < variables[j].DefRange.Start.Byte
+	})
+
+	return variables
+}
+
+// CollectTraversals returns every scope traversal in body whose root name is
+// root, e.g. all `var.*` references when root is "var". Traversals inside
+// template interpolations are included.
+func CollectTraversals(body hcl.Body, root string) []hcl.Traversal {
+	var traversals []hcl.Traversal
+
+	syntaxBody, ok := body.(*hclsyntax.Body)
+	if !ok {
+		return traversals
+	}
+
+	hclsyntax.VisitAll(syntaxBody, func(node hclsyntax.Node) hcl.Diagnostics {
+		if expr, ok := node.(*hclsyntax.ScopeTraversalExpr); ok && expr.Traversal.RootName() == root {
+			traversals = append(traversals, expr.Traversal)
+		}
+		return nil
+	})
+
+	return traversals
+}
+
+// FindTraversal returns the scope traversal under pos, or nil if there is none.
+func FindTraversal(body hcl.Body, pos hcl.Pos) hcl.Traversal {
+	var traversal hcl.Traversal
+
+	syntaxBody, ok := body.(*hclsyntax.Body)
+	if !ok {
+		return nil
+	}
+
+	hclsyntax.VisitAll(syntaxBody, func(node hclsyntax.Node) hcl.Diagnostics {
+		if expr, ok := node.(*hclsyntax.ScopeTraversalExpr); ok && expr.SrcRange.ContainsPos(pos) {
+			traversal = expr.Traversal
+		}
+		return nil
+	})
+
+	return traversal
+}
+
+// labelNameRange strips the surrounding quotes from the range of a quoted
+// block label so that it only covers the label name.
+func labelNameRange(label string, r hcl.Range) hcl.Range {
+	if r.End.Byte-r.Start.Byte != len(label)+2 {
+		return r
+	}
+
+	r.Start.Byte += 1
+	r.Start.Column += 1
+	r.End.Byte -= 1
+	r.End.Column -= 1
+
+	return r
+}
+
+// traversalName returns the attribute name directly following the root of a
+// traversal, e.g. "image" for `var.image.tag`, along with the range of the
+// name without its leading dot.
+func traversalName(traversal hcl.Traversal) (string, hcl.Range, bool) {
+	if len(traversal) < 2 {
+		return "", hcl.Range{}, false
+	}
+
+	attr, ok := traversal[1].(hcl.TraverseAttr)
+	if !ok {
+		return "", hcl.Range{}, false
+	}
+
+	r := attr.SrcRange
+	if r.End.Byte-r.Start.Byte == len(attr.Name)+1 {
+		r.Start.Byte += 1
+		r.Start.Column += 1
+	}
+
+	return attr.Name, r, true
+}
+
+// ConstraintType returns the type constraint of the variable, which is
+// cty.DynamicPseudoType when the variable has no `type` attribute, along with
+// the defaults of optional object attributes declared in the constraint.
+func (v Variable) ConstraintType() (cty.Type, *typeexpr.Defaults, hcl.Diagnostics) {
+	if v.Type == nil {
+		return cty.DynamicPseudoType, nil, nil
+	}
+
+	return typeexpr.TypeConstraintWithDefaults(v.Type)
+}
+
+// CollectVariableDiagnostics validates the `variable` blocks declared at the
+// root of body: type constraints must be valid type expressions, defaults
+// must be static values convertible to the declared type and every validation
+// condition must refer to the variable it validates.
+func CollectVariableDiagnostics(body hcl.Body) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+
+	bodyContent, _, _ := body.PartialContent(schema.RootBodySchema.ToHCLSchema())
+
+	for _, b := range bodyContent.Blocks.OfType("variable") {
+		if len(b.Labels) == 0 {
+			continue
+		}
+
+		name := b.Labels[0]
+
+		variableContent, _, _ := b.Body.PartialContent(schema.VariableSchema.ToHCLSchema())
+
+		variable := Variable{Name: name}
+		if attr := variableContent.Attributes["type"]; attr != nil {
+			variable.Type = attr.Expr
+		}
+
+		ty, defaults, typeDiags := variable.ConstraintType()
+		diags = diags.Extend(typeDiags)
+
+		if attr := variableContent.Attributes["default"]; attr != nil {
+			val, valDiags := attr.Expr.Value(nil)
+			diags = diags.Extend(valDiags)
+
+			if !valDiags.HasErrors() && !typeDiags.HasErrors() {
+				if defaults != nil {
+					val = defaults.Apply(val)
+				}
+
+				if _, err := convert.Convert(val, ty); err != nil {
+					diags = diags.Append(&hcl.Diagnostic{
+						Severity: hcl.DiagError,
+						Summary:  "Invalid default value for variable",
+						Detail:   fmt.Sprintf("This default value is not compatible with the variable's type constraint: %s.", err),
+						Subject:  attr.Expr.Range().Ptr(),
+					})
+				}
+			}
+		}
+
+		if attr := variableContent.Attributes["sensitive"]; attr != nil {
+			val, valDiags := attr.Expr.Value(nil)
+			diags = diags.Extend(valDiags)
+
+			if !valDiags.HasErrors() {
+				if _, err := convert.Convert(val, cty.Bool); err != nil {
+					diags = diags.Append(&hcl.Diagnostic{
+						Severity: hcl.DiagError,
+						Summary:  "Invalid sensitive value for variable",
+						Detail:   "The \"sensitive\" argument must be either true or false.",
+						Subject:  attr.Expr.Range().Ptr(),
+					})
+				}
+			}
+		}
+
+		for _, validation := range variableContent.Blocks.OfType("validation") {
+			validationContent, _, _ := validation.Body.PartialContent(schema.VariableValidationSchema.ToHCLSchema())
+
+			attr := validationContent.Attributes["condition"]
+			if attr == nil {
+				continue
+			}
+
+			refersToVariable := false
+			for _, traversal := range attr.Expr.Variables() {
+				if n, _, ok := traversalName(traversal); ok && traversal.RootName() == "var" && n == name {
+					refersToVariable = true
+				}
+			}
+
+			if !refersToVariable {
+				diags = diags.Append(&hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid variable validation condition",
+					Detail:   fmt.Sprintf("The condition for variable %q must refer to var.%s in order to test incoming values.", name, name),
+					Subject:  attr.Expr.Range().Ptr(),
+				})
+			}
+		}
+	}
+
+	return diags
+}
+
+// CollectVariableReferenceDiagnostics compares the `var.*` references of body
+// with the variables declared in files, which is expected to contain the file
+// being edited along with the siblings in its scope, see scopedFiles.
+// References to undeclared variables and variables declared more than once
+// are errors, while variables declared in body but never referenced are
+// warnings.
+func CollectVariableReferenceDiagnostics(body hcl.Body, files map[string]*hcl.File) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+
+	declarations := map[string][]Variable{}
+	used := map[string]bool{}
+
+	for _, filename := range sortedFilenames(files) {
+		for _, v := range CollectVariables(files[filename].Body) {
+			declarations[v.Name] = append(declarations[v.Name], v)
+		}
+
+		for _, traversal := range CollectTraversals(files[filename].Body, "var") {
+			if name, _, ok := traversalName(traversal); ok {
+				used[name] = true
+			}
+		}
+	}
+
+	for _, traversal := range CollectTraversals(body, "var") {
+		name, r, ok := traversalName(traversal)
+		if !ok {
+			continue
+		}
+
+		if len(declarations[name]) == 0 {
+			diags = diags.Append(&hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Reference to undeclared input variable",
+				Detail:   fmt.Sprintf("An input variable with the name %q has not been declared.", name),
+				Subject:  r.Ptr(),
+				Extra:    rules.DiagnosticExtra("undeclared-variable"),
+			})
+		}
+	}
+
+	for _, v := range CollectVariables(body) {
+		if previous := declarations[v.Name]; len(previous) > 0 && previous[0].NameRange != v.NameRange {
+			first := previous[0]
+			diags = diags.Append(&hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Duplicate variable declaration",
+				Detail:   fmt.Sprintf("A variable named %q was already declared at %s:%d. Variable names must be unique.", v.Name, filepath.Base(first.NameRange.Filename), first.NameRange.Start.Line),
+				Subject:  v.NameRange.Ptr(),
+				Extra:    rules.DiagnosticExtra("duplicate-variable"),
+			})
+		}
+
+		if !used[v.Name] {
+			diags = diags.Append(&hcl.Diagnostic{
+				Severity: hcl.DiagWarning,
+				Summary:  "Unused variable",
+				Detail:   fmt.Sprintf("The variable %q is declared but never used.", v.Name),
+				Subject:  v.NameRange.Ptr(),
+				Extra:    rules.DiagnosticExtra("unused-variable"),
+			})
+		}
+	}
+
+	return diags
+}
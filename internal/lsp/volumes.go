@@ -0,0 +1,269 @@
+package lsp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/loczek/nomad-ls/internal/rules"
+	"github.com/zclconf/go-cty/cty"
+	"go.lsp.dev/protocol"
+)
+
+// volumeMountAttributes is the attribute of a volume_mount block naming the
+// group volume it mounts, in the form expected by stringValueAt.
+var volumeMountAttributes = map[string]string{
+	"volume_mount": "volume",
+}
+
+// volumeMount is a volume_mount block of a task referencing a group volume.
+type volumeMount struct {
+	Block  *hclsyntax.Block
+	Volume string
+
+	// Range is the range of the volume name without its quotes.
+	Range hcl.Range
+}
+
+// groupVolumes returns the volume blocks of group by name.
+func groupVolumes(group *hclsyntax.Block) map[string]*hclsyntax.Block {
+	volumes := map[string]*hclsyntax.Block{}
+
+	for _, b := range group.Body.Blocks {
+		if b.Type == "volume" && len(b.Labels) > 0 {
+			volumes[b.Labels[0]] = b
+		}
+	}
+
+	return volumes
+}
+
+// groupVolumeMounts returns the volume_mount blocks of the tasks of group
+// whose volume is statically known.
+func groupVolumeMounts(group *hclsyntax.Block) []volumeMount {
+	var mounts []volumeMount
+
+	for _, task := range group.Body.Blocks {
+		if task.Type != "task" {
+			continue
+		}
+
+		for _, b := range task.Body.Blocks {
+			if b.Type != "volume_mount" || b.Body.Attributes["volume"] == nil {
+				continue
+			}
+
+			expr := b.Body.Attributes["volume"].Expr
+
+			val, diags := expr.Value(nil)
+			if diags.HasErrors() || val.Type() != cty.String || !val.IsKnown() || val.IsNull() {
+				continue
+			}
+
+			mounts = append(mounts, volumeMount{
+				Block:  b,
+				Volume: val.AsString(),
+				Range:  labelNameRange(val.AsString(), expr.Range()),
+			})
+		}
+	}
+
+	return mounts
+}
+
+// CollectVolumeDiagnostics cross-checks the volume_mount blocks of every task
+// with the volumes declared in its group. Mounts of undeclared volumes are
+// errors, while writable mounts of read-only volumes and read-only settings
+// conflicting with the access mode of a volume are warnings.
+func CollectVolumeDiagnostics(body hcl.Body) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+
+	for _, group := range jobGroups(body) {
+		volumes := groupVolumes(group)
+
+		names := mapKeys(volumes)
+		sort.Strings(names)
+
+		for _, name := range names {
+			diags = diags.Extend(checkVolumeAccessMode(volumes[name]))
+		}
+
+		for _, mount := range groupVolumeMounts(group) {
+			volume, ok := volumes[mount.Volume]
+			if !ok {
+				detail := fmt.Sprintf("No volume named %q is declared in group %q.", mount.Volume, blockLabel(group))
+				if suggestion := closestName(mount.Volume, names); suggestion != "" {
+					detail += fmt.Sprintf(" Did you mean %q?", suggestion)
+				}
+
+				diags = diags.Append(&hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Undefined volume",
+					Detail:   detail,
+					Subject:  mount.Range.Ptr(),
+					Extra:    rules.DiagnosticExtra("undefined-volume"),
+				})
+
+				continue
+			}
+
+			readOnly, ok := staticBool(mount.Block.Body, "read_only")
+			if !ok || readOnly {
+				continue
+			}
+
+			if volumeReadOnly(volume) {
+				diags = diags.Append(&hcl.Diagnostic{
+					Severity: hcl.DiagWarning,
+					Summary:  "Writable mount of a read-only volume",
+					Detail:   fmt.Sprintf("The volume %q is read-only, so it cannot be mounted with `read_only = false`.", mount.Volume),
+					Subject:  mount.Block.Body.Attributes["read_only"].Expr.Range().Ptr(),
+					Extra:    rules.DiagnosticExtra("read-only-volume"),
+				})
+			}
+		}
+	}
+
+	return diags
+}
+
+// checkVolumeAccessMode reports a `read_only` setting of volume contradicting
+// its `access_mode`, e.g. a read-only volume with a writer access mode.
+func checkVolumeAccessMode(volume *hclsyntax.Block) hcl.Diagnostics {
+	readOnly, ok := staticBool(volume.Body, "read_only")
+	if !ok {
+		return nil
+	}
+
+	accessMode, ok := staticString(volume.Body, "access_mode")
+	if !ok || accessMode == "" {
+		return nil
+	}
+
+	reader := strings.HasSuffix(accessMode, "-reader-only")
+	if reader == readOnly {
+		return nil
+	}
+
+	detail := fmt.Sprintf("The access mode %q only allows reading, but the volume is not read-only.", accessMode)
+	if readOnly {
+		detail = fmt.Sprintf("The access mode %q requests write access, but the volume is read-only.", accessMode)
+	}
+
+	return hcl.Diagnostics{
+		{
+			Severity: hcl.DiagWarning,
+			Summary:  "Inconsistent volume access mode",
+			Detail:   detail,
+			Subject:  volume.Body.Attributes["access_mode"].Expr.Range().Ptr(),
+		},
+	}
+}
+
+// volumeReadOnly reports whether volume is read-only, either explicitly or
+// because its access mode only allows reading.
+func volumeReadOnly(volume *hclsyntax.Block) bool {
+	if readOnly, ok := staticBool(volume.Body, "read_only"); ok {
+		return readOnly
+	}
+
+	accessMode, _ := staticString(volume.Body, "access_mode")
+
+	return strings.HasSuffix(accessMode, "-reader-only")
+}
+
+// CollectVolumeCompletions completes the names of the volumes declared in the
+// group enclosing offset within the `volume` of a volume_mount block. The
+// boolean result is false when no volume name is expected at offset.
+func CollectVolumeCompletions(body hcl.Body, offset int) ([]protocol.CompletionItem, bool) {
+	for _, group := range jobGroups(body) {
+		r, ok := stringValueAt(group.Body, "group", volumeMountAttributes, offset)
+		if !ok {
+			continue
+		}
+
+		volumes := groupVolumes(group)
+
+		names := mapKeys(volumes)
+		sort.Strings(names)
+
+		items := []protocol.CompletionItem{}
+
+		for _, name := range names {
+			detail := "volume"
+			if volumeType, ok := staticString(volumes[name].Body, "type"); ok && volumeType != "" {
+				detail = volumeType + " volume"
+			}
+
+			items = append(items, protocol.CompletionItem{
+				Label:  name,
+				Kind:   protocol.CompletionItemKindReference,
+				Detail: detail,
+				TextEdit: &protocol.TextEdit{
+					Range:   protocolRange(r),
+					NewText: name,
+				},
+			})
+		}
+
+		return items, true
+	}
+
+	return nil, false
+}
+
+// CollectVolumeDefinitions returns the declaration of the group volume named
+// by the volume_mount under pos.
+func CollectVolumeDefinitions(body hcl.Body, pos hcl.Pos) []hcl.Range {
+	var ranges []hcl.Range
+
+	for _, group := range jobGroups(body) {
+		volumes := groupVolumes(group)
+
+		for _, mount := range groupVolumeMounts(group) {
+			if !mount.Block.Body.Attributes["volume"].Expr.Range().ContainsPos(pos) {
+				continue
+			}
+
+			if volume, ok := volumes[mount.Volume]; ok {
+				ranges = append(ranges, labelNameRange(mount.Volume, volume.LabelRanges[0]))
+			}
+		}
+	}
+
+	return ranges
+}
+
+// staticBool returns the value of the bool attribute name in body when it is
+// statically known.
+func staticBool(body *hclsyntax.Body, name string) (bool, bool) {
+	attr := body.Attributes[name]
+	if attr == nil {
+		return false, false
+	}
+
+	val, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() || val.Type() != cty.Bool || !val.IsKnown() || val.IsNull() {
+		return false, false
+	}
+
+	return val.True(), true
+}
+
+// staticString returns the value of the string attribute name in body when it
+// is statically known.
+func staticString(body *hclsyntax.Body, name string) (string, bool) {
+	attr := body.Attributes[name]
+	if attr == nil {
+		return "", false
+	}
+
+	val, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() || val.Type() != cty.String || !val.IsKnown() || val.IsNull() {
+		return "", false
+	}
+
+	return val.AsString(), true
+}
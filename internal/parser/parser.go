@@ -1,6 +1,10 @@
 package parser
 
 import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 
 	"github.com/hashicorp/hcl/v2"
@@ -8,14 +12,20 @@ import (
 )
 
 type Parser struct {
-	files map[string]*hcl.File
-	mu    sync.Mutex
+	files     map[string]*hcl.File
+	workspace map[string]*hcl.File
+	mu        sync.Mutex
+
+	varFilePatterns  []string
+	selectedVarFiles map[string][]string
 }
 
 func NewParser() *Parser {
 	return &Parser{
-		files: map[string]*hcl.File{},
-		mu:    sync.Mutex{},
+		files:            map[string]*hcl.File{},
+		workspace:        map[string]*hcl.File{},
+		mu:               sync.Mutex{},
+		selectedVarFiles: map[string][]string{},
 	}
 }
 
@@ -51,3 +61,131 @@ func (p *Parser) RemoveHCL(filename string) {
 func (p *Parser) Files() map[string]*hcl.File {
 	return p.files
 }
+
+// Siblings returns the nomad files living in the same directory as filename,
+// including filename itself. Files open in the editor take precedence over
+// the workspace index, which in turn takes precedence over the disk.
+func (p *Parser) Siblings(filename string) map[string]*hcl.File {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	dir := filepath.Dir(filename)
+	siblings := map[string]*hcl.File{}
+
+	for name, file := range p.files {
+		if filepath.Dir(name) == dir && !p.isVarFile(name, file) {
+			siblings[name] = file
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return siblings
+	}
+
+	for _, entry := range entries {
+		name := filepath.Join(dir, entry.Name())
+		if entry.IsDir() || !IsNomadFile(name) || siblings[name] != nil {
+			continue
+		}
+
+		if file := p.workspace[name]; file != nil {
+			siblings[name] = file
+		} else if file := parseFile(name); file != nil {
+			siblings[name] = file
+		}
+	}
+
+	return siblings
+}
+
+// IndexWorkspace walks the given root directories and parses every nomad file
+// found, so that features like workspace symbols work for files that are not
+// open in the editor. Hidden directories are skipped.
+func (p *Parser) IndexWorkspace(roots []string) {
+	for _, root := range roots {
+		filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+
+			if d.IsDir() {
+				if path != root && strings.HasPrefix(d.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			if IsNomadFile(path) {
+				p.IndexFile(path)
+			}
+
+			return nil
+		})
+	}
+}
+
+// IndexFile (re)parses filename from disk into the workspace index. Files
+// that no longer exist are removed from the index.
+func (p *Parser) IndexFile(filename string) {
+	file := parseFile(filename)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if file == nil {
+		delete(p.workspace, filename)
+		return
+	}
+
+	p.workspace[filename] = file
+}
+
+// WorkspaceFiles returns every indexed workspace file, with files open in the
+// editor taking precedence over their contents on disk.
+func (p *Parser) WorkspaceFiles() map[string]*hcl.File {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	files := make(map[string]*hcl.File, len(p.workspace))
+
+	for name, file := range p.workspace {
+		files[name] = file
+	}
+
+	for name, file := range p.files {
+		files[name] = file
+	}
+
+	return files
+}
+
+// File returns the open file named filename, falling back to parsing it from
+// disk. It returns nil when the file cannot be read.
+func (p *Parser) File(filename string) *hcl.File {
+	p.mu.Lock()
+	file := p.files[filename]
+	p.mu.Unlock()
+
+	if file != nil {
+		return file
+	}
+
+	return parseFile(filename)
+}
+
+func parseFile(filename string) *hcl.File {
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		return nil
+	}
+
+	file, _ := hclsyntax.ParseConfig(src, filename, hcl.InitialPos)
+
+	return file
+}
+
+// IsNomadFile reports whether filename looks like a nomad job specification.
+func IsNomadFile(filename string) bool {
+	return strings.HasSuffix(filename, ".nomad") || strings.HasSuffix(filename, ".nomad.hcl")
+}
@@ -0,0 +1,192 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// DefaultVarFilePatterns are the glob patterns, matched against the base name
+// of a file, recognising var-files when none are configured.
+var DefaultVarFilePatterns = []string{"*.vars.hcl"}
+
+// varFileJobDirective links a var-file to the job it sets variables for when
+// it appears in a comment at the top of the var-file, e.g.
+// `# nomad-ls:job api.nomad.hcl`. The path is relative to the var-file.
+const varFileJobDirective = "nomad-ls:job"
+
+// SetVarFilePatterns replaces the glob patterns recognising var-files.
+func (p *Parser) SetVarFilePatterns(patterns []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.varFilePatterns = patterns
+}
+
+// IsVarFile reports whether filename is a var-file, either because its name
+// matches one of the var-file patterns or because it is linked to a job with
+// a `nomad-ls:job` comment.
+func (p *Parser) IsVarFile(filename string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.isVarFile(filename, p.files[filename])
+}
+
+func (p *Parser) isVarFile(filename string, file *hcl.File) bool {
+	patterns := p.varFilePatterns
+	if patterns == nil {
+		patterns = DefaultVarFilePatterns
+	}
+
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, filepath.Base(filename)); ok {
+			return true
+		}
+	}
+
+	if file == nil || IsNomadFile(filename) {
+		return false
+	}
+
+	return VarFileJob(filename, file.Bytes) != ""
+}
+
+// VarFileJob returns the job file a var-file is linked to with a
+// `nomad-ls:job` comment among the comments at the top of src, or an empty
+// string when there is none.
+func VarFileJob(filename string, src []byte) string {
+	scanner := bufio.NewScanner(bytes.NewReader(src))
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		comment, ok := strings.CutPrefix(line, "#")
+		if !ok {
+			comment, ok = strings.CutPrefix(line, "//")
+		}
+		if !ok {
+			return ""
+		}
+
+		job, ok := strings.CutPrefix(strings.TrimSpace(comment), varFileJobDirective)
+		if !ok || strings.TrimSpace(job) == "" {
+			continue
+		}
+
+		job = strings.TrimSpace(job)
+		if !filepath.IsAbs(job) {
+			job = filepath.Join(filepath.Dir(filename), job)
+		}
+
+		return job
+	}
+
+	return ""
+}
+
+// SelectVarFiles sets the var-files the job in jobFilename is evaluated
+// against, overriding the var-files linked to it with `nomad-ls:job`
+// comments. Passing no var-files restores the linked ones.
+func (p *Parser) SelectVarFiles(jobFilename string, varFilenames []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(varFilenames) == 0 {
+		delete(p.selectedVarFiles, jobFilename)
+		return
+	}
+
+	p.selectedVarFiles[jobFilename] = varFilenames
+}
+
+// VarFiles returns the var-files the job in jobFilename is evaluated against,
+// which are either the selected ones or the var-files in the directory of the
+// job that are linked to it with a `nomad-ls:job` comment.
+func (p *Parser) VarFiles(jobFilename string) []*hcl.File {
+	p.mu.Lock()
+	selected, ok := p.selectedVarFiles[jobFilename]
+	p.mu.Unlock()
+
+	if !ok {
+		selected = p.linkedVarFiles(jobFilename)
+	}
+
+	var varFiles []*hcl.File
+
+	for _, name := range selected {
+		if file := p.File(name); file != nil {
+			varFiles = append(varFiles, file)
+		}
+	}
+
+	return varFiles
+}
+
+func (p *Parser) linkedVarFiles(jobFilename string) []string {
+	dir := filepath.Dir(jobFilename)
+
+	names := map[string]bool{}
+
+	p.mu.Lock()
+	for name := range p.files {
+		if filepath.Dir(name) == dir {
+			names[name] = true
+		}
+	}
+	p.mu.Unlock()
+
+	if entries, err := os.ReadDir(dir); err == nil {
+		for _, entry := range entries {
+			if !entry.IsDir() && filepath.Ext(entry.Name()) == ".hcl" {
+				names[filepath.Join(dir, entry.Name())] = true
+			}
+		}
+	}
+
+	var linked []string
+
+	for name := range names {
+		if name == jobFilename || IsNomadFile(name) {
+			continue
+		}
+
+		file := p.File(name)
+		if file != nil && VarFileJob(name, file.Bytes) == jobFilename {
+			linked = append(linked, name)
+		}
+	}
+
+	sort.Strings(linked)
+
+	return linked
+}
+
+// Jobs returns the job files declaring the variables set by the var-file in
+// varFilename, which is the job linked with a `nomad-ls:job` comment or
+// otherwise the job files in the same directory.
+func (p *Parser) Jobs(varFilename string) map[string]*hcl.File {
+	var src []byte
+	if file := p.File(varFilename); file != nil {
+		src = file.Bytes
+	}
+
+	if job := VarFileJob(varFilename, src); job != "" {
+		jobs := map[string]*hcl.File{}
+		if file := p.File(job); file != nil {
+			jobs[job] = file
+		}
+
+		return jobs
+	}
+
+	return p.Siblings(varFilename)
+}
@@ -0,0 +1,248 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"go.lsp.dev/protocol"
+)
+
+// ConfigFileNames are the names of the project configuration files looked up
+// at the root of the workspace, in order of precedence.
+var ConfigFileNames = []string{".nomad-ls.hcl", ".nomad-ls.json"}
+
+// Severities are the severities rules can be set to in the configuration, by
+// name. hcl has no severities below warnings, so informations and hints are
+// the protocol severities, which diagnostics are published with by value.
+var Severities = map[string]hcl.DiagnosticSeverity{
+	"error":       hcl.DiagError,
+	"warning":     hcl.DiagWarning,
+	"information": hcl.DiagnosticSeverity(protocol.DiagnosticSeverityInformation),
+	"hint":        hcl.DiagnosticSeverity(protocol.DiagnosticSeverityHint),
+}
+
+// Config is the project configuration of the rules, e.g.
+//
+//	rule "canary-promotion" {
+//	  severity = "error"
+//	}
+//
+//	policy {
+//	  required_meta       = ["team"]
+//	  allowed_datacenters = ["eu-west-1", "eu-west-2"]
+//	  max_memory          = 4096
+//	}
+type Config struct {
+	RuleConfigs []*RuleConfig `hcl:"rule,block"`
+	Policy      *Policy       `hcl:"policy,block"`
+}
+
+// RuleConfig enables or disables a rule and overrides its severity.
+type RuleConfig struct {
+	ID       string    `hcl:"id,label"`
+	Enabled  *bool     `hcl:"enabled,optional"`
+	Severity *string   `hcl:"severity,optional"`
+	Range    hcl.Range `hcl:",def_range"`
+}
+
+// Policy holds the conventions enforced on every job by the policy rules.
+// Rules whose values are not set are not checked.
+type Policy struct {
+	// RequiredMeta are the keys the meta of every job has to set.
+	RequiredMeta []string `hcl:"required_meta,optional"`
+
+	// AllowedDatacenters are the datacenters, or glob patterns matching
+	// them, jobs are allowed to run in.
+	AllowedDatacenters []string `hcl:"allowed_datacenters,optional"`
+
+	// MaxMemory is the most memory, in megabytes, a task can request.
+	MaxMemory *int64 `hcl:"max_memory,optional"`
+}
+
+// LoadConfig reads the configuration file found in dir. The configuration is
+// nil when dir has none, and the returned filename is the file that was read.
+func LoadConfig(dir string) (*Config, string, hcl.Diagnostics) {
+	for _, name := range ConfigFileNames {
+		filename := filepath.Join(dir, name)
+
+		src, err := os.ReadFile(filename)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		if err != nil {
+			return nil, filename, hcl.Diagnostics{
+				{
+					Severity: hcl.DiagError,
+					Summary:  "Failed to read configuration",
+					Detail:   err.Error(),
+					Extra:    DiagnosticExtra("config"),
+				},
+			}
+		}
+
+		config, diags := ParseConfig(src, filename)
+
+		return config, filename, diags
+	}
+
+	return nil, "", nil
+}
+
+// ParseConfig parses a configuration file, written in JSON when filename has
+// a `.json` extension and in HCL otherwise.
+func ParseConfig(src []byte, filename string) (*Config, hcl.Diagnostics) {
+	p := hclparse.NewParser()
+
+	var file *hcl.File
+	var diags hcl.Diagnostics
+
+	if strings.HasSuffix(filename, ".json") {
+		file, diags = p.ParseJSON(src, filename)
+	} else {
+		file, diags = p.ParseHCL(src, filename)
+	}
+
+	if diags.HasErrors() {
+		return nil, configDiagnostics(diags)
+	}
+
+	config := &Config{}
+	diags = diags.Extend(gohcl.DecodeBody(file.Body, nil, config))
+
+	for _, rc := range config.RuleConfigs {
+		if rc.Severity == nil {
+			continue
+		}
+
+		if _, ok := Severities[*rc.Severity]; !ok {
+			names := mapKeys(Severities)
+			sort.Strings(names)
+
+			diags = diags.Append(&hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid severity",
+				Detail:   fmt.Sprintf("Invalid severity %q for rule %q, expected one of %s.", *rc.Severity, rc.ID, strings.Join(names, ", ")),
+				Subject:  rc.Range.Ptr(),
+			})
+		}
+	}
+
+	if diags.HasErrors() {
+		return nil, configDiagnostics(diags)
+	}
+
+	return config, configDiagnostics(diags)
+}
+
+// configDiagnostics attaches the `config` rule ID to the diagnostics of a
+// configuration file, including the syntax and decoding errors of hcl.
+func configDiagnostics(diags hcl.Diagnostics) hcl.Diagnostics {
+	for _, d := range diags {
+		if d.Extra == nil {
+			d.Extra = DiagnosticExtra("config")
+		}
+	}
+
+	return diags
+}
+
+// Rules returns the rules enabled by c, with their severities overridden by
+// c: the default rules followed by the policy rules whose values are set. A
+// nil configuration enables the default rules.
+func (c *Config) Rules() []Rule {
+	if c == nil {
+		return Default
+	}
+
+	all := append([]Rule{}, Default...)
+	if c.Policy != nil {
+		all = append(all, c.Policy.rules()...)
+	}
+
+	var enabled []Rule
+
+	for _, rule := range all {
+		if !c.Enabled(rule.ID) {
+			continue
+		}
+
+		if rc := c.ruleConfig(rule.ID); rc != nil && rc.Severity != nil {
+			rule.Severity = Severities[*rc.Severity]
+		}
+
+		enabled = append(enabled, rule)
+	}
+
+	return enabled
+}
+
+// Apply removes the diagnostics of the rules disabled by c from diags and
+// overrides the severities of the others. Diagnostics without a rule ID are
+// left as is.
+func (c *Config) Apply(diags hcl.Diagnostics) hcl.Diagnostics {
+	if c == nil {
+		return diags
+	}
+
+	var applied hcl.Diagnostics
+
+	for _, diag := range diags {
+		if !c.Enabled(ID(diag)) {
+			continue
+		}
+
+		if rc := c.ruleConfig(ID(diag)); rc != nil && rc.Severity != nil {
+			copied := *diag
+			copied.Severity = Severities[*rc.Severity]
+			diag = &copied
+		}
+
+		applied = append(applied, diag)
+	}
+
+	return applied
+}
+
+// Enabled reports whether c enables the rule id. Rules are enabled unless
+// disabled explicitly.
+func (c *Config) Enabled(id string) bool {
+	if c == nil {
+		return true
+	}
+
+	rc := c.ruleConfig(id)
+
+	return rc == nil || rc.Enabled == nil || *rc.Enabled
+}
+
+// ruleConfig returns the configuration of the rule id, the last one winning
+// when it is configured more than once.
+func (c *Config) ruleConfig(id string) *RuleConfig {
+	var config *RuleConfig
+
+	for _, rc := range c.RuleConfigs {
+		if rc.ID == id {
+			config = rc
+		}
+	}
+
+	return config
+}
+
+func mapKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	return keys
+}
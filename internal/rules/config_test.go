@@ -0,0 +1,132 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+func TestParseConfig(t *testing.T) {
+	config, diags := ParseConfig([]byte(`
+rule "canary-promotion" {
+  enabled = false
+}
+
+rule "update-deadlines" {
+  severity = "warning"
+}
+
+policy {
+  max_memory = 4096
+}
+`), ".nomad-ls.hcl")
+	if diags.HasErrors() {
+		t.Fatal(diags.Error())
+	}
+
+	if len(config.RuleConfigs) != 2 || *config.RuleConfigs[0].Enabled || *config.RuleConfigs[1].Severity != "warning" {
+		t.Errorf("unexpected rule configs: %+v", config.RuleConfigs)
+	}
+
+	if config.Policy == nil || *config.Policy.MaxMemory != 4096 {
+		t.Errorf("unexpected policy: %+v", config.Policy)
+	}
+
+	config, diags = ParseConfig([]byte(`{"rule": {"scaling-count": {"enabled": false}}}`), ".nomad-ls.json")
+	if diags.HasErrors() || len(config.RuleConfigs) != 1 || config.RuleConfigs[0].ID != "scaling-count" {
+		t.Errorf("unexpected JSON config: %+v, %s", config, diags.Error())
+	}
+
+	for _, src := range []string{
+		`rule "update-deadlines" { severity = "fatal" }`,
+		`rule "update-deadlines" { unknown = true }`,
+		`rule "update-deadlines" {`,
+	} {
+		config, diags = ParseConfig([]byte(src), ".nomad-ls.hcl")
+		if config != nil || !diags.HasErrors() {
+			t.Errorf("expected an error for %q, recieved: %+v", src, config)
+		}
+
+		for _, d := range diags {
+			if ID(d) != "config" {
+				t.Errorf("expected the config rule ID, recieved: %+v", d)
+			}
+		}
+	}
+}
+
+func TestConfigRules(t *testing.T) {
+	if rules := (*Config)(nil).Rules(); len(rules) != len(Default) {
+		t.Errorf("expected the default rules, recieved: %+v", rules)
+	}
+
+	disabled := false
+	warning := "warning"
+	limit := int64(1024)
+
+	config := &Config{
+		RuleConfigs: []*RuleConfig{
+			{ID: "canary-promotion", Enabled: &disabled},
+			{ID: "periodic-job-type", Severity: &warning},
+			{ID: "max-memory", Severity: &warning},
+		},
+		Policy: &Policy{MaxMemory: &limit},
+	}
+
+	severities := map[string]hcl.DiagnosticSeverity{}
+	for _, rule := range config.Rules() {
+		severities[rule.ID] = rule.Severity
+	}
+
+	if _, ok := severities["canary-promotion"]; ok {
+		t.Errorf("expected canary-promotion to be disabled")
+	}
+
+	if severities["periodic-job-type"] != hcl.DiagWarning || severities["max-memory"] != hcl.DiagWarning {
+		t.Errorf("expected overridden severities, recieved: %v", severities)
+	}
+
+	if len(severities) != len(Default) {
+		t.Errorf("expected the other default rules and max-memory, recieved: %v", severities)
+	}
+}
+
+func TestConfigApply(t *testing.T) {
+	disabled := false
+	hint := "hint"
+
+	config := &Config{
+		RuleConfigs: []*RuleConfig{
+			{ID: "unused-variable", Enabled: &disabled},
+			{ID: "unknown-function", Severity: &hint},
+		},
+	}
+
+	diags := hcl.Diagnostics{
+		{Severity: hcl.DiagWarning, Summary: "unused", Extra: DiagnosticExtra("unused-variable")},
+		{Severity: hcl.DiagWarning, Summary: "unknown", Extra: DiagnosticExtra("unknown-function")},
+		{Severity: hcl.DiagError, Summary: "syntax"},
+	}
+
+	var messages []string
+	for _, d := range config.Apply(diags) {
+		messages = append(messages, d.Summary)
+
+		if d.Summary == "unknown" && d.Severity != Severities["hint"] {
+			t.Errorf("expected a hint, recieved: %+v", d)
+		}
+	}
+
+	if strings.Join(messages, ",") != "unknown,syntax" {
+		t.Errorf("expected: %q, recieved: %q", "unknown,syntax", messages)
+	}
+
+	if diags[1].Severity != hcl.DiagWarning {
+		t.Errorf("expected the diagnostics to be left as is, recieved: %+v", diags[1])
+	}
+
+	if applied := (*Config)(nil).Apply(diags); len(applied) != len(diags) {
+		t.Errorf("expected every diagnostic, recieved: %+v", applied)
+	}
+}
@@ -0,0 +1,301 @@
+package rules
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Default is the rule set checked against every job.
+var Default = []Rule{
+	{
+		ID:       "canary-promotion",
+		Severity: hcl.DiagWarning,
+		Message:  "Canaries are never promoted automatically",
+		Check:    checkCanaryPromotion,
+	},
+	{
+		ID:       "periodic-job-type",
+		Severity: hcl.DiagError,
+		Message:  "Periodic job is not a batch job",
+		Check:    batchOnly("periodic"),
+	},
+	{
+		ID:       "parameterized-job-type",
+		Severity: hcl.DiagError,
+		Message:  "Parameterized job is not a batch job",
+		Check:    batchOnly("parameterized"),
+	},
+	{
+		ID:       "batch-job-update",
+		Severity: hcl.DiagError,
+		Message:  "Update strategy of a batch job",
+		Check:    checkBatchUpdate,
+	},
+	{
+		ID:       "update-deadlines",
+		Severity: hcl.DiagError,
+		Message:  "Inconsistent update deadlines",
+		Check:    checkUpdateDeadlines,
+	},
+	{
+		ID:       "scaling-count",
+		Severity: hcl.DiagError,
+		Message:  "Count outside of scaling bounds",
+		Check:    checkScalingCount,
+	},
+}
+
+// batchJobTypes are the job types which can be periodic or parameterized.
+var batchJobTypes = []string{"batch", "sysbatch"}
+
+// Defaults of the update block, used when a deadline is compared to one that
+// is not set.
+const (
+	defaultMinHealthyTime   = 10 * time.Second
+	defaultHealthyDeadline  = 5 * time.Minute
+	defaultProgressDeadline = 10 * time.Minute
+)
+
+// updateStrategy is the update block of a group merged with the update block
+// of its job, which the group inherits unset attributes from.
+type updateStrategy struct {
+	bodies []*hclsyntax.Body
+}
+
+// updateStrategies returns the update strategy of every group of job that has
+// an update block of its own or inherits one.
+func updateStrategies(job *Job) []updateStrategy {
+	var jobUpdate *hclsyntax.Body
+	if blocks := job.blocks(job.Block.Body, "update"); len(blocks) > 0 {
+		jobUpdate = blocks[0].Body
+	}
+
+	var strategies []updateStrategy
+
+	for _, group := range job.Groups() {
+		var s updateStrategy
+
+		if blocks := job.blocks(group.Body, "update"); len(blocks) > 0 {
+			s.bodies = append(s.bodies, blocks[0].Body)
+		}
+
+		if jobUpdate != nil {
+			s.bodies = append(s.bodies, jobUpdate)
+		}
+
+		if len(s.bodies) > 0 {
+			strategies = append(strategies, s)
+		}
+	}
+
+	return strategies
+}
+
+// attribute returns the body setting the attribute name, the update block of
+// the group taking precedence over the one of the job.
+func (s updateStrategy) attribute(name string) (*hclsyntax.Body, bool) {
+	for _, body := range s.bodies {
+		if body.Attributes[name] != nil {
+			return body, true
+		}
+	}
+
+	return nil, false
+}
+
+func checkCanaryPromotion(job *Job) []Violation {
+	var violations []Violation
+
+	for _, s := range updateStrategies(job) {
+		body, ok := s.attribute("canary")
+		if !ok {
+			continue
+		}
+
+		canary, attr, ok := job.number(body, "canary")
+		if !ok || canary <= 0 {
+			continue
+		}
+
+		if body, ok := s.attribute("auto_promote"); ok {
+			val, _, ok := job.value(body, "auto_promote", cty.Bool)
+			if !ok || val.True() {
+				continue
+			}
+		}
+
+		violations = append(violations, Violation{
+			Detail:  fmt.Sprintf("The update strategy deploys %d canaries without `auto_promote = true`, so every deployment waits for a manual `nomad deployment promote`.", canary),
+			Subject: attr.Expr.Range(),
+		})
+	}
+
+	return violations
+}
+
+// batchOnly returns a check reporting blockType blocks in jobs which are not
+// of a batch type.
+func batchOnly(blockType string) func(job *Job) []Violation {
+	return func(job *Job) []Violation {
+		jobType, ok := job.Type()
+		if !ok || contains(batchJobTypes, jobType) {
+			return nil
+		}
+
+		var violations []Violation
+
+		for _, b := range job.blocks(job.Block.Body, blockType) {
+			violations = append(violations, Violation{
+				Detail:  fmt.Sprintf("The %s block is only valid in jobs of type \"batch\" or \"sysbatch\", but the job is of type %q.", blockType, jobType),
+				Subject: b.TypeRange,
+			})
+		}
+
+		return violations
+	}
+}
+
+func checkBatchUpdate(job *Job) []Violation {
+	jobType, ok := job.Type()
+	if !ok || !contains(batchJobTypes, jobType) {
+		return nil
+	}
+
+	blocks := job.blocks(job.Block.Body, "update")
+	for _, group := range job.Groups() {
+		blocks = append(blocks, job.blocks(group.Body, "update")...)
+	}
+
+	var violations []Violation
+
+	for _, b := range blocks {
+		violations = append(violations, Violation{
+			Detail:  fmt.Sprintf("Jobs of type %q are not deployed, so the update block has no effect and is rejected by nomad.", jobType),
+			Subject: b.TypeRange,
+		})
+	}
+
+	return violations
+}
+
+// deadline is a duration attribute of an update strategy.
+type deadline struct {
+	Name  string
+	Value time.Duration
+	Attr  *hclsyntax.Attribute
+}
+
+func (s updateStrategy) deadline(job *Job, name string, def time.Duration) (deadline, bool) {
+	body, ok := s.attribute(name)
+	if !ok {
+		return deadline{Name: name, Value: def}, true
+	}
+
+	d, attr, ok := job.duration(body, name)
+
+	return deadline{Name: name, Value: d, Attr: attr}, ok
+}
+
+func checkUpdateDeadlines(job *Job) []Violation {
+	var violations []Violation
+
+	for _, s := range updateStrategies(job) {
+		minHealthyTime, ok1 := s.deadline(job, "min_healthy_time", defaultMinHealthyTime)
+		healthyDeadline, ok2 := s.deadline(job, "healthy_deadline", defaultHealthyDeadline)
+		progressDeadline, ok3 := s.deadline(job, "progress_deadline", defaultProgressDeadline)
+
+		if ok1 && ok2 {
+			if v, ok := compareDeadlines(minHealthyTime, healthyDeadline); ok {
+				violations = append(violations, v)
+			}
+		}
+
+		// A progress_deadline of zero disables it.
+		if ok2 && ok3 && progressDeadline.Value != 0 {
+			if v, ok := compareDeadlines(healthyDeadline, progressDeadline); ok {
+				violations = append(violations, v)
+			}
+		}
+	}
+
+	return violations
+}
+
+// compareDeadlines reports shorter not being shorter than longer. The
+// violation is reported on longer, or on shorter when longer is not set.
+func compareDeadlines(shorter, longer deadline) (Violation, bool) {
+	if shorter.Value < longer.Value || (shorter.Attr == nil && longer.Attr == nil) {
+		return Violation{}, false
+	}
+
+	attr := longer.Attr
+	if attr == nil {
+		attr = shorter.Attr
+	}
+
+	return Violation{
+		Detail:  fmt.Sprintf("`%s` (%s) must be shorter than `%s` (%s).", shorter.Name, shorter.Value, longer.Name, longer.Value),
+		Subject: attr.Expr.Range(),
+	}, true
+}
+
+func checkScalingCount(job *Job) []Violation {
+	var violations []Violation
+
+	for _, group := range job.Groups() {
+		for _, scaling := range job.blocks(group.Body, "scaling") {
+			// Task scaling blocks are labelled by the resource they scale.
+			if len(scaling.Labels) > 0 {
+				continue
+			}
+
+			minimum, minAttr, hasMin := job.number(scaling.Body, "min")
+			maximum, _, hasMax := job.number(scaling.Body, "max")
+
+			if hasMin && hasMax && minimum > maximum {
+				violations = append(violations, Violation{
+					Detail:  fmt.Sprintf("`min` (%d) must not be greater than `max` (%d).", minimum, maximum),
+					Subject: minAttr.Expr.Range(),
+				})
+				continue
+			}
+
+			// Without a count, nomad starts with the minimum of the scaling
+			// policy.
+			count, countAttr, ok := job.number(group.Body, "count")
+			if !ok {
+				continue
+			}
+
+			switch {
+			case hasMin && count < minimum:
+				violations = append(violations, Violation{
+					Detail:  fmt.Sprintf("The count of group %q (%d) is lower than the `min` of its scaling policy (%d).", blockLabel(group), count, minimum),
+					Subject: countAttr.Expr.Range(),
+				})
+			case hasMax && count > maximum:
+				violations = append(violations, Violation{
+					Detail:  fmt.Sprintf("The count of group %q (%d) is greater than the `max` of its scaling policy (%d).", blockLabel(group), count, maximum),
+					Subject: countAttr.Expr.Range(),
+				})
+			}
+
+		}
+	}
+
+	return violations
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
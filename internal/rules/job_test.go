@@ -0,0 +1,340 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// ruleTest is a job checked against a single rule, expecting the source of
+// the subjects of the violations.
+type ruleTest struct {
+	name     string
+	src      string
+	expected []string
+}
+
+func runRuleTests(t *testing.T, rule Rule, tests []ruleTest) {
+	t.Helper()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, diags := hclsyntax.ParseConfig([]byte(tt.src), "job.nomad.hcl", hcl.InitialPos)
+			if diags.HasErrors() {
+				t.Fatal(diags.Error())
+			}
+
+			var subjects []string
+			for _, d := range Run(file.Body, nil, []Rule{rule}) {
+				if ID(d) != rule.ID || d.Severity != rule.Severity {
+					t.Errorf("unexpected diagnostic: %+v", d)
+				}
+
+				subjects = append(subjects, string(d.Subject.SliceBytes(file.Bytes)))
+			}
+
+			if strings.Join(subjects, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("expected: %q, recieved: %q", tt.expected, subjects)
+			}
+		})
+	}
+}
+
+func defaultRule(t *testing.T, id string) Rule {
+	t.Helper()
+
+	for _, rule := range Default {
+		if rule.ID == id {
+			return rule
+		}
+	}
+
+	t.Fatalf("no default rule %q", id)
+
+	return Rule{}
+}
+
+func TestCanaryPromotion(t *testing.T) {
+	runRuleTests(t, defaultRule(t, "canary-promotion"), []ruleTest{
+		{
+			name: "canaries without auto_promote",
+			src: `job "a" {
+  group "g" {
+    update {
+      canary = 2
+    }
+  }
+}
+`,
+			expected: []string{"2"},
+		},
+		{
+			name: "auto_promote",
+			src: `job "a" {
+  group "g" {
+    update {
+      canary = 2
+      auto_promote = true
+    }
+  }
+}
+`,
+		},
+		{
+			name: "inherited from the job",
+			src: `job "a" {
+  update {
+    canary = 1
+  }
+  group "g" {}
+}
+`,
+			expected: []string{"1"},
+		},
+		{
+			name: "auto_promote inherited from the job",
+			src: `job "a" {
+  update {
+    auto_promote = true
+  }
+  group "g" {
+    update {
+      canary = 1
+    }
+  }
+}
+`,
+		},
+		{
+			name: "no canaries",
+			src: `job "a" {
+  group "g" {
+    update {
+      canary = 0
+    }
+  }
+}
+`,
+		},
+	})
+}
+
+func TestPeriodicJobType(t *testing.T) {
+	runRuleTests(t, defaultRule(t, "periodic-job-type"), []ruleTest{
+		{
+			name: "service job",
+			src: `job "a" {
+  periodic {
+    crons = ["@daily"]
+  }
+}
+`,
+			expected: []string{"periodic"},
+		},
+		{
+			name: "batch job",
+			src: `job "a" {
+  type = "batch"
+  periodic {
+    crons = ["@daily"]
+  }
+}
+`,
+		},
+		{
+			name: "sysbatch job",
+			src: `job "a" {
+  type = "sysbatch"
+  periodic {
+    crons = ["@daily"]
+  }
+}
+`,
+		},
+		{
+			name: "unknown type",
+			src: `job "a" {
+  type = var.type
+  periodic {
+    crons = ["@daily"]
+  }
+}
+`,
+		},
+	})
+}
+
+func TestParameterizedJobType(t *testing.T) {
+	runRuleTests(t, defaultRule(t, "parameterized-job-type"), []ruleTest{
+		{
+			name: "system job",
+			src: `job "a" {
+  type = "system"
+  parameterized {}
+}
+`,
+			expected: []string{"parameterized"},
+		},
+		{
+			name: "batch job",
+			src: `job "a" {
+  type = "batch"
+  parameterized {}
+}
+`,
+		},
+	})
+}
+
+func TestBatchJobUpdate(t *testing.T) {
+	runRuleTests(t, defaultRule(t, "batch-job-update"), []ruleTest{
+		{
+			name: "batch job",
+			src: `job "a" {
+  type = "batch"
+  update {}
+  group "g" {
+    update {}
+  }
+}
+`,
+			expected: []string{"update", "update"},
+		},
+		{
+			name: "sysbatch job",
+			src: `job "a" {
+  type = "sysbatch"
+  group "g" {
+    update {}
+  }
+}
+`,
+			expected: []string{"update"},
+		},
+		{
+			name: "service job",
+			src: `job "a" {
+  update {}
+}
+`,
+		},
+	})
+}
+
+func TestUpdateDeadlines(t *testing.T) {
+	runRuleTests(t, defaultRule(t, "update-deadlines"), []ruleTest{
+		{
+			name: "min_healthy_time above healthy_deadline",
+			src: `job "a" {
+  group "g" {
+    update {
+      min_healthy_time = "10m"
+      healthy_deadline = "5m"
+    }
+  }
+}
+`,
+			expected: []string{`"5m"`},
+		},
+		{
+			name: "healthy_deadline above the default progress_deadline",
+			src: `job "a" {
+  group "g" {
+    update {
+      healthy_deadline = "15m"
+    }
+  }
+}
+`,
+			expected: []string{`"15m"`},
+		},
+		{
+			name: "disabled progress_deadline",
+			src: `job "a" {
+  group "g" {
+    update {
+      healthy_deadline = "15m"
+      progress_deadline = 0
+    }
+  }
+}
+`,
+		},
+		{
+			name: "consistent deadlines",
+			src: `job "a" {
+  group "g" {
+    update {
+      min_healthy_time = "30s"
+      healthy_deadline = "5m"
+      progress_deadline = "10m"
+    }
+  }
+}
+`,
+		},
+	})
+}
+
+func TestScalingCount(t *testing.T) {
+	runRuleTests(t, defaultRule(t, "scaling-count"), []ruleTest{
+		{
+			name: "min above max",
+			src: `job "a" {
+  group "g" {
+    scaling {
+      min = 5
+      max = 2
+    }
+  }
+}
+`,
+			expected: []string{"5"},
+		},
+		{
+			name: "count below min",
+			src: `job "a" {
+  group "g" {
+    count = 1
+    scaling {
+      min = 2
+      max = 4
+    }
+  }
+}
+`,
+			expected: []string{"1"},
+		},
+		{
+			name: "count above max",
+			src: `job "a" {
+  group "g" {
+    count = 5
+    scaling {
+      min = 2
+      max = 4
+    }
+  }
+}
+`,
+			expected: []string{"5"},
+		},
+		{
+			name: "task scaling",
+			src: `job "a" {
+  group "g" {
+    count = 5
+    task "t" {
+      scaling "cpu" {
+        min = 2
+        max = 4
+      }
+    }
+  }
+}
+`,
+		},
+	})
+}
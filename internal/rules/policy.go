@@ -0,0 +1,182 @@
+package rules
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// rules returns the policy rules whose values are set.
+func (p *Policy) rules() []Rule {
+	var rules []Rule
+
+	if len(p.RequiredMeta) > 0 {
+		rules = append(rules, Rule{
+			ID:       "required-meta",
+			Severity: hcl.DiagWarning,
+			Message:  "Missing required meta",
+			Check:    requiredMeta(p.RequiredMeta),
+		})
+	}
+
+	if len(p.AllowedDatacenters) > 0 {
+		rules = append(rules, Rule{
+			ID:       "allowed-datacenters",
+			Severity: hcl.DiagError,
+			Message:  "Datacenter not allowed",
+			Check:    allowedDatacenters(p.AllowedDatacenters),
+		})
+	}
+
+	if p.MaxMemory != nil {
+		rules = append(rules, Rule{
+			ID:       "max-memory",
+			Severity: hcl.DiagError,
+			Message:  "Memory above the allowed maximum",
+			Check:    maxMemory(*p.MaxMemory),
+		})
+	}
+
+	return rules
+}
+
+// requiredMeta returns a check reporting jobs whose meta, written either as a
+// block or as a map, does not set all of keys.
+func requiredMeta(keys []string) func(job *Job) []Violation {
+	return func(job *Job) []Violation {
+		subject := job.Block.TypeRange
+		if len(job.Block.LabelRanges) > 0 {
+			subject = job.Block.LabelRanges[0]
+		}
+
+		set := map[string]bool{}
+
+		if blocks := job.blocks(job.Block.Body, "meta"); len(blocks) > 0 {
+			subject = blocks[0].TypeRange
+
+			for _, b := range blocks {
+				for name := range b.Body.Attributes {
+					set[name] = true
+				}
+			}
+		} else if attr := job.Block.Body.Attributes["meta"]; attr != nil {
+			subject = attr.NameRange
+
+			val, diags := attr.Expr.Value(job.Ctx)
+			if diags.HasErrors() || !val.IsKnown() || val.IsNull() || !(val.Type().IsObjectType() || val.Type().IsMapType()) {
+				return nil
+			}
+
+			for it := val.ElementIterator(); it.Next(); {
+				key, _ := it.Element()
+				set[key.AsString()] = true
+			}
+		}
+
+		var missing []string
+		for _, key := range keys {
+			if !set[key] {
+				missing = append(missing, fmt.Sprintf("%q", key))
+			}
+		}
+
+		if len(missing) == 0 {
+			return nil
+		}
+
+		return []Violation{
+			{
+				Detail:  fmt.Sprintf("The meta of job %q does not set %s, required by the project configuration.", blockLabel(job.Block), strings.Join(missing, ", ")),
+				Subject: subject,
+			},
+		}
+	}
+}
+
+// allowedDatacenters returns a check reporting the datacenters of jobs that
+// match none of allowed.
+func allowedDatacenters(allowed []string) func(job *Job) []Violation {
+	return func(job *Job) []Violation {
+		attr := job.Block.Body.Attributes["datacenters"]
+		if attr == nil {
+			return nil
+		}
+
+		exprs := []hclsyntax.Expression{attr.Expr}
+		if tuple, ok := attr.Expr.(*hclsyntax.TupleConsExpr); ok {
+			exprs = tuple.Exprs
+		}
+
+		var violations []Violation
+
+		for _, expr := range exprs {
+			val, diags := expr.Value(job.Ctx)
+			if diags.HasErrors() || !val.IsWhollyKnown() || val.IsNull() {
+				continue
+			}
+
+			var datacenters []cty.Value
+			if val.Type() == cty.String {
+				datacenters = []cty.Value{val}
+			} else if val.CanIterateElements() {
+				for it := val.ElementIterator(); it.Next(); {
+					_, v := it.Element()
+					datacenters = append(datacenters, v)
+				}
+			}
+
+			for _, dc := range datacenters {
+				if dc.Type() != cty.String || dc.IsNull() || datacenterAllowed(dc.AsString(), allowed) {
+					continue
+				}
+
+				violations = append(violations, Violation{
+					Detail:  fmt.Sprintf("The datacenter %q is not allowed by the project configuration, expected one of %s.", dc.AsString(), strings.Join(allowed, ", ")),
+					Subject: expr.Range(),
+				})
+			}
+		}
+
+		return violations
+	}
+}
+
+func datacenterAllowed(dc string, allowed []string) bool {
+	for _, pattern := range allowed {
+		if ok, _ := path.Match(pattern, dc); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// maxMemory returns a check reporting tasks requesting more than limit
+// megabytes of memory.
+func maxMemory(limit int64) func(job *Job) []Violation {
+	return func(job *Job) []Violation {
+		var violations []Violation
+
+		for _, group := range job.Groups() {
+			for _, task := range job.blocks(group.Body, "task") {
+				for _, resources := range job.blocks(task.Body, "resources") {
+					memory, attr, ok := job.number(resources.Body, "memory")
+					if !ok || memory <= limit {
+						continue
+					}
+
+					violations = append(violations, Violation{
+						Detail:  fmt.Sprintf("The task %q requests %d MB of memory, more than the %d MB allowed by the project configuration.", blockLabel(task), memory, limit),
+						Subject: attr.Expr.Range(),
+					})
+				}
+			}
+		}
+
+		return violations
+	}
+}
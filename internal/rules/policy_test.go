@@ -0,0 +1,147 @@
+package rules
+
+import "testing"
+
+func policyRule(t *testing.T, policy *Policy, id string) Rule {
+	t.Helper()
+
+	for _, rule := range policy.rules() {
+		if rule.ID == id {
+			return rule
+		}
+	}
+
+	t.Fatalf("no policy rule %q", id)
+
+	return Rule{}
+}
+
+func TestRequiredMeta(t *testing.T) {
+	rule := policyRule(t, &Policy{RequiredMeta: []string{"team", "owner"}}, "required-meta")
+
+	runRuleTests(t, rule, []ruleTest{
+		{
+			name:     "no meta",
+			src:      `job "a" {}`,
+			expected: []string{`"a"`},
+		},
+		{
+			name: "meta block missing a key",
+			src: `job "a" {
+  meta {
+    team = "ops"
+  }
+}
+`,
+			expected: []string{"meta"},
+		},
+		{
+			name: "meta block",
+			src: `job "a" {
+  meta {
+    team = "ops"
+    owner = "me"
+  }
+}
+`,
+		},
+		{
+			name: "meta map missing a key",
+			src: `job "a" {
+  meta = { owner = "me" }
+}
+`,
+			expected: []string{"meta"},
+		},
+		{
+			name: "meta map",
+			src: `job "a" {
+  meta = { team = "ops", owner = "me" }
+}
+`,
+		},
+	})
+}
+
+func TestAllowedDatacenters(t *testing.T) {
+	rule := policyRule(t, &Policy{AllowedDatacenters: []string{"eu-west-*", "dc1"}}, "allowed-datacenters")
+
+	runRuleTests(t, rule, []ruleTest{
+		{
+			name: "allowed",
+			src: `job "a" {
+  datacenters = ["dc1", "eu-west-1"]
+}
+`,
+		},
+		{
+			name: "not allowed",
+			src: `job "a" {
+  datacenters = ["dc1", "us-east-1"]
+}
+`,
+			expected: []string{`"us-east-1"`},
+		},
+		{
+			name: "unknown value",
+			src: `job "a" {
+  datacenters = [var.dc]
+}
+`,
+		},
+		{
+			name: "no datacenters",
+			src:  `job "a" {}`,
+		},
+	})
+}
+
+func TestMaxMemory(t *testing.T) {
+	limit := int64(1024)
+	rule := policyRule(t, &Policy{MaxMemory: &limit}, "max-memory")
+
+	runRuleTests(t, rule, []ruleTest{
+		{
+			name: "above the maximum",
+			src: `job "a" {
+  group "g" {
+    task "t" {
+      resources {
+        memory = 2048
+      }
+    }
+  }
+}
+`,
+			expected: []string{"2048"},
+		},
+		{
+			name: "at the maximum",
+			src: `job "a" {
+  group "g" {
+    task "t" {
+      resources {
+        memory = 1024
+      }
+    }
+  }
+}
+`,
+		},
+		{
+			name: "no resources",
+			src: `job "a" {
+  group "g" {
+    task "t" {}
+  }
+}
+`,
+		},
+	})
+}
+
+func TestPolicyRulesOfUnsetValues(t *testing.T) {
+	if rules := (&Policy{}).rules(); len(rules) != 0 {
+		t.Errorf("expected no rules, recieved: %+v", rules)
+	}
+}
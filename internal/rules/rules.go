@@ -0,0 +1,207 @@
+// Package rules checks job specifications against the nomad rules spanning
+// several attributes or blocks, which cannot be expressed by the schema of a
+// single body.
+package rules
+
+import (
+	"time"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// Rule is a check run against every job of a file.
+type Rule struct {
+	// ID identifies the rule, e.g. `update-deadlines`.
+	ID string
+
+	// Severity is the severity of the diagnostics reported by the rule.
+	Severity hcl.DiagnosticSeverity
+
+	// Message summarises the rule and is the summary of its diagnostics.
+	Message string
+
+	// Check returns the violations of the rule in job.
+	Check func(job *Job) []Violation
+}
+
+// Violation is a part of a job breaking a rule.
+type Violation struct {
+	Detail  string
+	Subject hcl.Range
+}
+
+// Job is a job block along with the context its expressions are evaluated
+// in, which may be nil.
+type Job struct {
+	Block *hclsyntax.Block
+	Ctx   *hcl.EvalContext
+}
+
+// Run checks every job in body against rules. Values are evaluated in ctx
+// and only statically known values are checked.
+func Run(body hcl.Body, ctx *hcl.EvalContext, rules []Rule) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+
+	syntaxBody, ok := body.(*hclsyntax.Body)
+	if !ok {
+		return diags
+	}
+
+	for _, b := range syntaxBody.Blocks {
+		if b.Type != "job" {
+			continue
+		}
+
+		job := &Job{Block: b, Ctx: ctx}
+
+		for _, rule := range rules {
+			seen := map[Violation]bool{}
+
+			for _, v := range rule.Check(job) {
+				if seen[v] {
+					continue
+				}
+				seen[v] = true
+
+				diags = diags.Append(&hcl.Diagnostic{
+					Severity: rule.Severity,
+					Summary:  rule.Message,
+					Detail:   v.Detail,
+					Subject:  v.Subject.Ptr(),
+					Extra:    diagnosticExtra{ruleID: rule.ID},
+				})
+			}
+		}
+	}
+
+	return diags
+}
+
+// diagnosticExtra is the extra information attached to the diagnostics
+// reported by a rule.
+type diagnosticExtra struct {
+	ruleID string
+}
+
+// DiagnosticExtra returns the extra information attaching the rule id to a
+// diagnostic reported outside of Run, so that it can be configured and
+// suppressed like the diagnostics of the rules.
+func DiagnosticExtra(id string) any {
+	return diagnosticExtra{ruleID: id}
+}
+
+// ID returns the ID of the rule that reported diag, or an empty string when
+// diag was not reported by a rule.
+func ID(diag *hcl.Diagnostic) string {
+	if extra, ok := hcl.DiagnosticExtra[diagnosticExtra](diag); ok {
+		return extra.ruleID
+	}
+
+	return ""
+}
+
+// Type returns the type of the job, which defaults to "service", and whether
+// it is statically known.
+func (j *Job) Type() (string, bool) {
+	val, _, ok := j.value(j.Block.Body, "type", cty.String)
+	if !ok {
+		if j.Block.Body.Attributes["type"] != nil {
+			return "", false
+		}
+
+		return "service", true
+	}
+
+	return val.AsString(), true
+}
+
+// Groups returns the group blocks of the job.
+func (j *Job) Groups() []*hclsyntax.Block {
+	return j.blocks(j.Block.Body, "group")
+}
+
+func (j *Job) blocks(body *hclsyntax.Body, blockType string) []*hclsyntax.Block {
+	var blocks []*hclsyntax.Block
+
+	for _, b := range body.Blocks {
+		if b.Type == blockType {
+			blocks = append(blocks, b)
+		}
+	}
+
+	return blocks
+}
+
+// value returns the value of the attribute name of body converted to ty,
+// along with the attribute. The boolean result is false when the attribute is
+// not set or its value is not statically known.
+func (j *Job) value(body *hclsyntax.Body, name string, ty cty.Type) (cty.Value, *hclsyntax.Attribute, bool) {
+	attr := body.Attributes[name]
+	if attr == nil {
+		return cty.NilVal, nil, false
+	}
+
+	val, diags := attr.Expr.Value(j.Ctx)
+	if diags.HasErrors() || !val.IsWhollyKnown() || val.IsNull() {
+		return cty.NilVal, attr, false
+	}
+
+	val, err := convert.Convert(val, ty)
+	if err != nil {
+		return cty.NilVal, attr, false
+	}
+
+	return val, attr, true
+}
+
+// number returns the value of the number attribute name of body as an int.
+func (j *Job) number(body *hclsyntax.Body, name string) (int64, *hclsyntax.Attribute, bool) {
+	val, attr, ok := j.value(body, name, cty.Number)
+	if !ok {
+		return 0, attr, false
+	}
+
+	n, accuracy := val.AsBigFloat().Int64()
+	if accuracy != 0 {
+		return 0, attr, false
+	}
+
+	return n, attr, true
+}
+
+// duration returns the value of the duration attribute name of body, which
+// is either a duration string or a number of nanoseconds.
+func (j *Job) duration(body *hclsyntax.Body, name string) (time.Duration, *hclsyntax.Attribute, bool) {
+	attr := body.Attributes[name]
+	if attr == nil {
+		return 0, nil, false
+	}
+
+	val, diags := attr.Expr.Value(j.Ctx)
+	if diags.HasErrors() || !val.IsWhollyKnown() || val.IsNull() {
+		return 0, attr, false
+	}
+
+	switch val.Type() {
+	case cty.String:
+		d, err := time.ParseDuration(val.AsString())
+		return d, attr, err == nil
+	case cty.Number:
+		n, accuracy := val.AsBigFloat().Int64()
+		return time.Duration(n), attr, accuracy == 0
+	}
+
+	return 0, attr, false
+}
+
+// blockLabel returns the first label of b, or an empty string if it has none.
+func blockLabel(b *hclsyntax.Block) string {
+	if len(b.Labels) == 0 {
+		return ""
+	}
+
+	return b.Labels[0]
+}
@@ -116,7 +116,7 @@ var CheckSchema = &schema.BodySchema{
 		},
 		"type": {
 			Description: lang.Markdown("This indicates the check types supported by Nomad. For Consul service checks, valid options are `grpc`, `http`, `script`, and `tcp`. For Nomad service checks, valid options are `http` and `tcp`."),
-			Constraint:  &schema.LiteralType{Type: cty.String},
+			Constraint:  stringValues("grpc", "http", "script", "tcp"),
 			IsRequired:  true,
 		},
 		"tls_server_name": {
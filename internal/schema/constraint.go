@@ -24,7 +24,7 @@ var ConstraintSchema = &schema.BodySchema{
 			DefaultValue: &schema.DefaultValue{
 				Value: cty.StringVal("="),
 			},
-			Constraint: &schema.LiteralType{Type: cty.String},
+			Constraint: stringValues("=", "==", "is", "!=", "not", ">", ">=", "<", "<=", "distinct_hosts", "distinct_property", "regexp", "set_contains", "set_contains_all", "set_contains_any", "version", "semver", "is_set", "is_not_set"),
 			IsOptional: true,
 		},
 		"value": {
@@ -0,0 +1,327 @@
+package schema
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/hashicorp/hcl-lang/schema"
+	"github.com/loczek/nomad-ls/internal/schema/drivers"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Format is the format the values of an attribute follow on top of their
+// type, such as a duration written as a string.
+type Format string
+
+const (
+	// FormatDuration is a Go duration such as `"30s"` or `"1h30m"`. Numbers
+	// are durations in nanoseconds and are not checked.
+	FormatDuration Format = "duration"
+
+	// FormatSize is a whole number of megabytes.
+	FormatSize Format = "size"
+
+	// FormatCron is a cron expression, such as `"*/15 * * * *"`, or one of
+	// the predefined expressions such as `"@daily"`.
+	FormatCron Format = "cron"
+
+	// FormatTimeZone is the name of a time zone in the IANA database.
+	FormatTimeZone Format = "time zone"
+)
+
+// attributeFormats are the formats of the attributes whose values are checked
+// beyond their type.
+var attributeFormats = map[*schema.AttributeSchema]Format{
+	TaskSchema.Attributes["kill_timeout"]:               FormatDuration,
+	TaskSchema.Attributes["shutdown_delay"]:             FormatDuration,
+	SidecarTaskSchema.Attributes["kill_timeout"]:        FormatDuration,
+	SidecarTaskSchema.Attributes["shutdown_delay"]:      FormatDuration,
+	GroupSchema.Attributes["shutdown_delay"]:            FormatDuration,
+	UpdateSchema.Attributes["min_healthy_time"]:         FormatDuration,
+	UpdateSchema.Attributes["healthy_deadline"]:         FormatDuration,
+	UpdateSchema.Attributes["progress_deadline"]:        FormatDuration,
+	UpdateSchema.Attributes["stagger"]:                  FormatDuration,
+	MigrateSchema.Attributes["min_healthy_time"]:        FormatDuration,
+	MigrateSchema.Attributes["healthy_deadline"]:        FormatDuration,
+	RescheduleSchema.Attributes["interval"]:             FormatDuration,
+	RescheduleSchema.Attributes["delay"]:                FormatDuration,
+	RescheduleSchema.Attributes["max_delay"]:            FormatDuration,
+	RestartSchema.Attributes["interval"]:                FormatDuration,
+	RestartSchema.Attributes["delay"]:                   FormatDuration,
+	CheckSchema.Attributes["interval"]:                  FormatDuration,
+	CheckSchema.Attributes["timeout"]:                   FormatDuration,
+	CheckRestartSchema.Attributes["grace"]:              FormatDuration,
+	ChangeScriptSchema.Attributes["timeout"]:            FormatDuration,
+	CsiPluginSchema.Attributes["health_timeout"]:        FormatDuration,
+	DisconnectSchema.Attributes["lost_after"]:           FormatDuration,
+	DisconnectSchema.Attributes["stop_on_client_after"]: FormatDuration,
+	GatewayProxySchema.Attributes["connect_timeout"]:    FormatDuration,
+	NetworkSchema.Attributes["image_pull_timeout"]:      FormatDuration,
+	TemplateSchema.Attributes["splay"]:                  FormatDuration,
+	TemplateSchema.Attributes["vault_grace"]:            FormatDuration,
+	WaitSchema.Attributes["min"]:                        FormatDuration,
+	WaitSchema.Attributes["max"]:                        FormatDuration,
+	EphemeralDiskSchema.Attributes["size"]:              FormatSize,
+	LogsSchema.Attributes["max_file_size"]:              FormatSize,
+	PeriodicSchema.Attributes["cron"]:                   FormatCron,
+	PeriodicSchema.Attributes["crons"]:                  FormatCron,
+	PeriodicSchema.Attributes["time_zone"]:              FormatTimeZone,
+	CronSchema.Attributes["timezone"]:                   FormatTimeZone,
+
+	// driver configs
+	drivers.DockerDriverSchema.Attributes["image_pull_timeout"]: FormatDuration,
+}
+
+// AttributeFormat returns the format of the values of attr, or an empty
+// format when they are only checked against the type of attr.
+func AttributeFormat(attr *schema.AttributeSchema) Format {
+	return attributeFormats[attr]
+}
+
+// FormatError describes a value not following its format. Start and End are
+// the byte offsets of the offending part of a string value, and are both zero
+// when the value as a whole is invalid.
+type FormatError struct {
+	Summary string
+	Detail  string
+	Start   int
+	End     int
+}
+
+// Validate checks that val, a single value of an attribute with format f,
+// follows the format. Values that are unknown, null or of an unexpected type
+// are left to the type checks.
+func (f Format) Validate(val cty.Value) *FormatError {
+	if !val.IsKnown() || val.IsNull() {
+		return nil
+	}
+
+	switch {
+	case f == FormatDuration && val.Type() == cty.String:
+		return validateDuration(val.AsString())
+	case f == FormatSize && val.Type() == cty.Number:
+		return validateSize(val.AsBigFloat())
+	case f == FormatCron && val.Type() == cty.String:
+		return validateCron(val.AsString())
+	case f == FormatTimeZone && val.Type() == cty.String:
+		return validateTimeZone(val.AsString())
+	}
+
+	return nil
+}
+
+func validateDuration(s string) *FormatError {
+	if _, err := time.ParseDuration(s); err != nil {
+		return &FormatError{
+			Summary: "Invalid duration",
+			Detail:  fmt.Sprintf("%q is not a valid duration. A duration is a sequence of numbers with a unit, such as \"30s\", \"5m\" or \"1h30m\". Valid units are \"ns\", \"us\", \"ms\", \"s\", \"m\" and \"h\".", s),
+		}
+	}
+
+	return nil
+}
+
+func validateSize(f *big.Float) *FormatError {
+	if !f.IsInt() || f.Sign() < 0 {
+		return &FormatError{
+			Summary: "Invalid size",
+			Detail:  fmt.Sprintf("%s is not a valid size. A size is a whole number of megabytes.", f.Text('f', -1)),
+		}
+	}
+
+	return nil
+}
+
+func validateTimeZone(s string) *FormatError {
+	if _, err := time.LoadLocation(s); err != nil {
+		return &FormatError{
+			Summary: "Invalid time zone",
+			Detail:  fmt.Sprintf("%q is not a time zone of the IANA database, such as \"UTC\" or \"America/New_York\".", s),
+		}
+	}
+
+	return nil
+}
+
+// cronPredefined are the predefined cron expressions.
+var cronPredefined = []string{"@yearly", "@annually", "@monthly", "@weekly", "@daily", "@hourly"}
+
+// cronField is a field of a cron expression along with the range of its
+// values and the names that can be used instead of numbers.
+type cronField struct {
+	Name  string
+	Min   int
+	Max   int
+	Names []string
+}
+
+var (
+	cronSecond     = cronField{Name: "second", Min: 0, Max: 59}
+	cronMinute     = cronField{Name: "minute", Min: 0, Max: 59}
+	cronHour       = cronField{Name: "hour", Min: 0, Max: 23}
+	cronDayOfMonth = cronField{Name: "day of month", Min: 1, Max: 31}
+	cronMonth      = cronField{Name: "month", Min: 1, Max: 12, Names: []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}}
+	cronDayOfWeek  = cronField{Name: "day of week", Min: 0, Max: 7, Names: []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}}
+	cronYear       = cronField{Name: "year", Min: 1970, Max: 2099}
+)
+
+// validateCron checks a cron expression the way cronexpr, which nomad uses,
+// parses it. Expressions have 5 fields (minute to day of week), 6 fields
+// (adding the year) or 7 fields (adding the second in front).
+func validateCron(s string) *FormatError {
+	trimmed := strings.TrimSpace(s)
+
+	if strings.HasPrefix(trimmed, "@") {
+		for _, predefined := range cronPredefined {
+			if trimmed == predefined {
+				return nil
+			}
+		}
+
+		start := strings.Index(s, trimmed)
+
+		return &FormatError{
+			Summary: "Invalid cron expression",
+			Detail:  fmt.Sprintf("%q is not a predefined cron expression, expected one of %s.", trimmed, strings.Join(cronPredefined, ", ")),
+			Start:   start,
+			End:     start + len(trimmed),
+		}
+	}
+
+	fields, starts := cronFields(s)
+
+	var spec []cronField
+	switch len(fields) {
+	case 5:
+		spec = []cronField{cronMinute, cronHour, cronDayOfMonth, cronMonth, cronDayOfWeek}
+	case 6:
+		spec = []cronField{cronMinute, cronHour, cronDayOfMonth, cronMonth, cronDayOfWeek, cronYear}
+	case 7:
+		spec = []cronField{cronSecond, cronMinute, cronHour, cronDayOfMonth, cronMonth, cronDayOfWeek, cronYear}
+	default:
+		return &FormatError{
+			Summary: "Invalid cron expression",
+			Detail:  fmt.Sprintf("A cron expression has 5 to 7 fields separated by spaces, but %q has %d.", s, len(fields)),
+		}
+	}
+
+	for i, field := range fields {
+		offset := starts[i]
+
+		for _, item := range strings.Split(field, ",") {
+			if msg := spec[i].validate(item); msg != "" {
+				return &FormatError{
+					Summary: "Invalid cron expression",
+					Detail:  msg,
+					Start:   offset,
+					End:     offset + len(item),
+				}
+			}
+
+			offset += len(item) + 1
+		}
+	}
+
+	return nil
+}
+
+// cronFields splits a cron expression into its fields, returning the byte
+// offset of every field as well.
+func cronFields(s string) ([]string, []int) {
+	var fields []string
+	var starts []int
+
+	start := -1
+	for i := 0; i <= len(s); i++ {
+		if i == len(s) || s[i] == ' ' || s[i] == '\t' {
+			if start >= 0 {
+				fields = append(fields, s[start:i])
+				starts = append(starts, start)
+				start = -1
+			}
+			continue
+		}
+
+		if start < 0 {
+			start = i
+		}
+	}
+
+	return fields, starts
+}
+
+// validate checks a single item of a comma separated cron field, such as
+// `*/15`, `1-5` or `MON`, returning a message describing the problem or an
+// empty string.
+func (f cronField) validate(item string) string {
+	if item == "" {
+		return fmt.Sprintf("The %s field has an empty value.", f.Name)
+	}
+
+	base, step, stepped := strings.Cut(item, "/")
+	if stepped {
+		if n, err := strconv.Atoi(step); err != nil || n <= 0 {
+			return fmt.Sprintf("%q is not a valid step for the %s field, expected a positive number.", step, f.Name)
+		}
+	}
+
+	switch {
+	case base == "*":
+		return ""
+	case base == "?" && (f.Name == cronDayOfMonth.Name || f.Name == cronDayOfWeek.Name):
+		return ""
+	case f.Name == cronDayOfMonth.Name && (base == "L" || base == "LW"):
+		return ""
+	case f.Name == cronDayOfMonth.Name && strings.HasSuffix(base, "W"):
+		return f.validateValue(strings.TrimSuffix(base, "W"), item)
+	case f.Name == cronDayOfWeek.Name && strings.HasSuffix(base, "L"):
+		return f.validateValue(strings.TrimSuffix(base, "L"), item)
+	case f.Name == cronDayOfWeek.Name && strings.Contains(base, "#"):
+		day, nth, _ := strings.Cut(base, "#")
+		if n, err := strconv.Atoi(nth); err != nil || n < 1 || n > 5 {
+			return fmt.Sprintf("%q is not a valid occurrence in %q, expected a number from 1 to 5.", nth, item)
+		}
+		return f.validateValue(day, item)
+	}
+
+	from, to, ranged := strings.Cut(base, "-")
+	if msg := f.validateValue(from, item); msg != "" {
+		return msg
+	}
+
+	if ranged {
+		return f.validateValue(to, item)
+	}
+
+	return ""
+}
+
+// validateValue checks a single number or name of the field, which is part of
+// item.
+func (f cronField) validateValue(value string, item string) string {
+	for _, name := range f.Names {
+		if strings.EqualFold(value, name) {
+			return ""
+		}
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		expected := fmt.Sprintf("a number from %d to %d", f.Min, f.Max)
+		if len(f.Names) > 0 {
+			expected += fmt.Sprintf(" or one of %s", strings.Join(f.Names, ", "))
+		}
+
+		return fmt.Sprintf("%q is not a valid value for the %s field in %q, expected %s.", value, f.Name, item, expected)
+	}
+
+	if n < f.Min || n > f.Max {
+		return fmt.Sprintf("%d is out of range for the %s field, expected a number from %d to %d.", n, f.Name, f.Min, f.Max)
+	}
+
+	return ""
+}
@@ -47,7 +47,7 @@ var JobSchema = &schema.BodySchema{
 		"type": {
 			Description:  lang.PlainText("Specifies the Nomad scheduler to use. Nomad provides the `service`, `system`, `batch`, and `sysbatch` schedulers."),
 			DefaultValue: &schema.DefaultValue{Value: cty.StringVal("service")},
-			Constraint:   &schema.LiteralType{Type: cty.String},
+			Constraint:   stringValues("service", "batch", "system", "sysbatch"),
 		},
 		// TODO: Update with docs later
 		"vault_token": {
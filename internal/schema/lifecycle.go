@@ -11,11 +11,8 @@ var LifecycleSchema = &schema.BodySchema{
 		// TODO: update docs
 		"hook": {
 			Description: lang.Markdown("Specifies when a task should be run within the lifecycle of a group. The following hooks are available:\n- `prestart` - Will be started immediately. The main tasks will not start until all prestart tasks with sidecar = false have completed successfully."),
-			DefaultValue: &schema.DefaultValue{
-				Value: cty.StringVal("default"),
-			},
-			Constraint: &schema.LiteralType{Type: cty.String},
-			IsRequired: true,
+			Constraint:  stringValues("prestart", "poststart", "poststop"),
+			IsRequired:  true,
 		},
 		"sidecar": {
 			Description: lang.Markdown("Controls whether a task is ephemeral or long-lived within the task group. If a lifecycle task is ephemeral (`sidecar = false`), the task will not be restarted after it completes successfully. If a lifecycle task is long-lived (`sidecar = true`) and terminates, it will be restarted as long as the allocation is running."),
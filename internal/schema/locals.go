@@ -0,0 +1,18 @@
+package schema
+
+import (
+	"github.com/hashicorp/hcl-lang/lang"
+	"github.com/hashicorp/hcl-lang/schema"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// LocalsSchema defines the schema for a `locals` block, whose attributes are
+// referenced elsewhere in the job as `local.<name>`.
+var LocalsSchema = &schema.BodySchema{
+	Description: lang.Markdown("Assigns a name to an expression, so it can be used multiple times within a job without repeating it. Local values are referenced as `local.<name>` and may refer to input variables and other local values."),
+	AnyAttribute: &schema.AttributeSchema{
+		Description: lang.Markdown("Defines a local value with the given name."),
+		Constraint:  &schema.LiteralType{Type: cty.DynamicPseudoType},
+		IsOptional:  true,
+	},
+}
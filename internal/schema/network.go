@@ -22,7 +22,7 @@ var NetworkSchema = &schema.BodySchema{
 			DefaultValue: &schema.DefaultValue{
 				Value: cty.StringVal("host"),
 			},
-			Constraint: &schema.LiteralType{Type: cty.String},
+			Constraint: stringValues("none", "bridge", "host", "cni/"),
 		},
 		"hostname": {
 			Description: lang.Markdown("The hostname assigned to the network namespace. This is currently only supported using the [Docker driver](https://developer.hashicorp.com/nomad/docs/job-declare/task-driver/docker) and when the [mode](https://developer.hashicorp.com/nomad/docs/job-specification/network#mode) is set to [`bridge`](https://developer.hashicorp.com/nomad/docs/job-specification/network#bridge). This parameter supports [interpolation](https://developer.hashicorp.com/nomad/docs/reference/runtime-variable-interpolation)."),
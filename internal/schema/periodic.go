@@ -11,10 +11,10 @@ var PeriodicSchema = &schema.BodySchema{
 		"cron": {
 			Description: lang.Markdown("Specifies a cron expression configuring the interval to launch the job. In addition to [cron-specific formats](https://github.com/hashicorp/cronexpr#implementation), this option also includes predefined expressions such as `@daily` or `@weekly`. Either `cron` or `crons` must be set, but not both."),
 			DefaultValue: &schema.DefaultValue{
-				Value: cty.ListVal([]cty.Value{cty.StringVal("")}),
+				Value: cty.StringVal(""),
 			},
 			IsDeprecated: true,
-			Constraint:   &schema.LiteralType{Type: cty.List(cty.String)},
+			Constraint:   &schema.LiteralType{Type: cty.String},
 		},
 		"crons": {
 			Description: lang.Markdown("A list of cron expressions configuring the intervals the job is launched at. The job runs at the next earliest time that matches any of the expressions. Supports predefined expressions such as `@daily` and `@weekly`. Refer to [the documentation](https://github.com/hashicorp/cronexpr#implementation) for full details about the supported cron specs and the predefined expressions. Either `cron` or `crons` must be set, but not both."),
@@ -32,10 +32,7 @@ var RescheduleSchema = &schema.BodySchema{
 		},
 		"delay_function": {
 			Description: lang.Markdown("Specifies the function that is used to calculate subsequent reschedule delays. The initial delay is specified by the delay parameter. `delay_function` has three possible values which are described below.\n- `constant` - The delay between reschedule attempts stays constant at the delay value.\n- `exponential` - The delay between reschedule attempts doubles.\n- `fibonacci` - The delay between reschedule attempts is calculated by adding the two most recent delays applied. For example if delay is set to 5 seconds, the next five reschedule attempts will be delayed by 5 seconds, 5 seconds, 10 seconds, 15 seconds, and 25 seconds respectively."),
-			DefaultValue: &schema.DefaultValue{
-				Value: cty.StringVal(""),
-			},
-			Constraint: &schema.LiteralType{Type: cty.String},
+			Constraint:  stringValues("constant", "exponential", "fibonacci"),
 		},
 		"max_delay": {
 			Description: lang.Markdown("is an upper bound on the delay beyond which it will not increase. This parameter is used when `delay_function` is `exponential` or `fibonacci`, and is ignored when `constant `delay is used."),
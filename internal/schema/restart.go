@@ -35,7 +35,7 @@ var RestartSchema = &schema.BodySchema{
 			DefaultValue: &schema.DefaultValue{
 				Value: cty.StringVal("fail"),
 			},
-			Constraint: &schema.LiteralType{Type: cty.String},
+			Constraint: stringValues("delay", "fail"),
 		},
 		"render_templates": {
 			Description: lang.Markdown("Specifies whether to re-render all templates when a task is restarted. If set to `true`, all templates will be re-rendered when the task restarts. This can be useful for re-fetching Vault secrets, even if the lease on the existing secrets has not yet expired."),
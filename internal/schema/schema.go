@@ -2,6 +2,7 @@ package schema
 
 import (
 	"github.com/hashicorp/hcl-lang/schema"
+	"github.com/zclconf/go-cty/cty"
 )
 
 var RootBodySchema = schema.BodySchema{
@@ -17,6 +18,10 @@ var RootBodySchema = schema.BodySchema{
 			Description: VariablesSchema.Description,
 			Body:        VariablesSchema,
 		},
+		"locals": {
+			Description: LocalsSchema.Description,
+			Body:        LocalsSchema,
+		},
 		"job": {
 			Description: JobSchema.Description,
 			Labels: []*schema.LabelSchema{
@@ -26,3 +31,15 @@ var RootBodySchema = schema.BodySchema{
 		},
 	},
 }
+
+// stringValues returns a constraint listing the given strings as the allowed
+// literal values of an attribute.
+func stringValues(values ...string) schema.OneOf {
+	constraint := make(schema.OneOf, 0, len(values))
+
+	for _, v := range values {
+		constraint = append(constraint, schema.LiteralValue{Value: cty.StringVal(v)})
+	}
+
+	return constraint
+}
@@ -12,7 +12,7 @@ var TemplateSchema = &schema.BodySchema{
 		"change_mode": {
 			Description:  lang.PlainText("Specifies the behavior Nomad should take if the rendered template changes. Nomad will always write the new contents of the template to the specified destination. The following possible values describe Nomad's action after writing the template to disk."),
 			DefaultValue: &schema.DefaultValue{Value: cty.StringVal("restart")},
-			Constraint:   &schema.LiteralType{Type: cty.String},
+			Constraint:   stringValues("noop", "restart", "signal", "script"),
 		},
 		"change_signal": {
 			Description:  lang.PlainText("Specifies the signal to send to the task as a string like `\"SIGUSR1\"` or `\"SIGINT\"`. This option is required if the `change_mode` is `signal`."),
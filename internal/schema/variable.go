@@ -6,17 +6,50 @@ import (
 	"github.com/zclconf/go-cty/cty"
 )
 
-// TODO: update docs and constraints
 var VariableSchema = &schema.BodySchema{
 	Description: lang.Markdown("Input variables serve as parameters for a Nomad job, allowing aspects of the job to be customized without altering the job's own source code.\nWhen you declare variables in the same file as the job specification, you can set their values using CLI options and environment variables."),
 	Attributes: map[string]*schema.AttributeSchema{
 		"type": {
-			Description: lang.Markdown("The type of HCL variable: `string`, `number`, `bool`."),
-			Constraint:  &schema.LiteralType{Type: cty.String},
+			Description: lang.Markdown("Specifies what value types are accepted for the variable, e.g. `string`, `number`, `bool`, `list(string)`, `map(number)` or `object({ name = string })`. The keyword `any` accepts a value of any type. If both `type` and `default` are specified, the default value must be convertible to the specified type."),
+			Constraint:  schema.TypeDeclaration{},
+			IsOptional:  true,
 		},
 		"default": {
-			Description: lang.Markdown("The default value used when no value for this variable is provided."),
+			Description: lang.Markdown("The default value used when no value for this variable is provided. A variable without a default is required and must be set with `-var`, `-var-file` or an environment variable."),
+			Constraint:  &schema.LiteralType{Type: cty.DynamicPseudoType},
+			IsOptional:  true,
+		},
+		"description": {
+			Description: lang.Markdown("Documents the purpose of the variable and what kind of value is expected."),
+			Constraint:  &schema.LiteralType{Type: cty.String},
+			IsOptional:  true,
+		},
+		"sensitive": {
+			Description:  lang.Markdown("Marks the variable as sensitive, which prevents its value from being shown by `nomad job run` and `nomad job plan`."),
+			DefaultValue: &schema.DefaultValue{Value: cty.BoolVal(false)},
+			Constraint:   &schema.LiteralType{Type: cty.Bool},
+			IsOptional:   true,
+		},
+	},
+	Blocks: map[string]*schema.BlockSchema{
+		"validation": {
+			Description: lang.Markdown("Specifies a custom validation rule for the variable. This can be provided multiple times to define additional rules."),
+			Body:        VariableValidationSchema,
+		},
+	},
+}
+
+var VariableValidationSchema = &schema.BodySchema{
+	Attributes: map[string]*schema.AttributeSchema{
+		"condition": {
+			Description: lang.Markdown("An expression that must use the value of the variable to return `true` if the value is valid, or `false` if it is invalid."),
+			Constraint:  &schema.LiteralType{Type: cty.Bool},
+			IsRequired:  true,
+		},
+		"error_message": {
+			Description: lang.Markdown("The message shown when `condition` evaluates to `false`. It should be at least one full sentence explaining the constraint that failed."),
 			Constraint:  &schema.LiteralType{Type: cty.String},
+			IsRequired:  true,
 		},
 	},
 }
@@ -17,7 +17,7 @@ var VaultSchema = &schema.BodySchema{
 		"change_mode": {
 			Description:  lang.Markdown("Specifies the behavior Nomad should take if the Vault token changes. The possible values are:\n\n- [`\"noop\"`](https://developer.hashicorp.com/nomad/docs/job-specification/vault#noop) - take no action (continue running the task)\n- [`\"restart\"`](https://developer.hashicorp.com/nomad/docs/job-specification/vault#restart) - restart the task\n- [`\"signal\"`](https://developer.hashicorp.com/nomad/docs/job-specification/vault#signal) - send a configurable signal to the task"),
 			DefaultValue: &schema.DefaultValue{Value: cty.StringVal("restart")},
-			Constraint:   &schema.LiteralType{Type: cty.String},
+			Constraint:   stringValues("noop", "restart", "signal"),
 			IsOptional:   true,
 		},
 		"change_signal": {
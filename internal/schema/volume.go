@@ -45,10 +45,7 @@ var VolumeSchema = &schema.BodySchema{
 		},
 		"access_mode": {
 			Description: lang.Markdown("Defines whether a volume should be available concurrently. The `access_mode` and `attachment_mode` together must exactly match one of the volume's `capability` blocks.\n\nFor CSI volumes the `access_mode` is required. Can be one of the following:\n\n- [`\"single-node-reader-only\"`](https://developer.hashicorp.com/nomad/docs/job-specification/volume#single-node-reader-only)\n- [`\"single-node-writer\"`](https://developer.hashicorp.com/nomad/docs/job-specification/volume#single-node-writer)\n- [`\"multi-node-reader-only\"`](https://developer.hashicorp.com/nomad/docs/job-specification/volume#multi-node-reader-only)\n- [`\"multi-node-single-writer\"`](https://developer.hashicorp.com/nomad/docs/job-specification/volume#multi-node-single-writer)\n- [`\"multi-node-multi-writer\"`](https://developer.hashicorp.com/nomad/docs/job-specification/volume#multi-node-multi-writer)\n\nMost CSI plugins support only single-node modes. Consult the documentation of the storage provider and CSI plugin.\n    \nFor dynamic host volumes the `access_mode` is optional. Can be one of the following:\n\n- [`\"single-node-writer\"`](https://developer.hashicorp.com/nomad/docs/job-specification/volume#single-node-writer-1)\n- [`\"single-node-reader-only\"`](https://developer.hashicorp.com/nomad/docs/job-specification/volume#single-node-reader-only-1)\n- [`\"single-node-single-writer\"`](https://developer.hashicorp.com/nomad/docs/job-specification/volume#single-node-single-writer)\n- [`\"single-node-multi-writer\"`](https://developer.hashicorp.com/nomad/docs/job-specification/volume#single-node-multi-writer)\n\nDefaults to `single-node-writer` unless `read_only = true`, in which case it defaults to `single-node-reader-only`."),
-			DefaultValue: &schema.DefaultValue{
-				Value: cty.StringVal(""),
-			},
-			Constraint: &schema.LiteralType{Type: cty.String},
+			Constraint:  stringValues("single-node-reader-only", "single-node-writer", "single-node-single-writer", "single-node-multi-writer", "multi-node-reader-only", "multi-node-single-writer", "multi-node-multi-writer"),
 		},
 		"attachment_mode": {
 			Description: lang.Markdown("The storage API used by the volume. One of `\"file-system\"` or `\"block-device\"`. The `access_mode` and `attachment_mode` together must exactly match one of the volume's `capability` blocks.\n-For CSI volumes the `attachment_mode` field is required. Most storage providers support `\"file-system\"`, to mount volumes using the CSI filesystem API. Some storage providers support `\"block-device\"`, which mounts the volume with the CSI block device API within the container.\n-For dynamic host volumes the `attachment_mode` field is optional and defaults to `\"file-system\"`."),